@@ -0,0 +1,204 @@
+package backend
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// EventFiles returns event.go with the domain events of the entity, e.g. UserCreated,
+// UserUpdated and UserDeleted, with the functions the core calls to create their outbox
+// events, and event_test.go with their tests. The events carry the entity without its
+// sensitive fields.
+func EventFiles(entity generator.Entity, modulePath string) ([]generator.File, error) {
+	if err := entity.Validate(); err != nil {
+		return nil, fmt.Errorf("Validate: %w", err)
+	}
+
+	primaryKey := "ID"
+	for _, field := range entity.Fields {
+		if field.PrimaryKey {
+			primaryKey = field.Name
+		}
+	}
+
+	code, err := eventCode(entity, primaryKey, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("eventCode: %w", err)
+	}
+	test, err := eventTest(entity, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("eventTest: %w", err)
+	}
+
+	dir := path.Join(BusinessDir, entity.Name)
+	return []generator.File{
+		{Path: path.Join(dir, "event.go"), Content: code},
+		{Path: path.Join(dir, "event_test.go"), Content: test},
+	}, nil
+}
+
+// OutboxTable creates the table of the outbox of the domain events, see pkg/outbox.
+func OutboxTable() Table {
+	return Table{
+		Name: "outbox_events",
+		Columns: []Column{
+			{Name: "id", Type: "uuid", NotNull: true, PrimaryKey: true},
+			{Name: "type", Type: "text", NotNull: true},
+			{Name: "aggregate", Type: "text", NotNull: true},
+			{Name: "aggregate_id", Type: "text", NotNull: true},
+			{Name: "payload", Type: "jsonb", NotNull: true},
+			{Name: "occurred_at", Type: "timestamptz", NotNull: true},
+			{Name: "published_at", Type: "timestamptz"},
+		},
+		Indexes: []string{"aggregate_id", "published_at"},
+	}
+}
+
+// eventPkgs maps the packages of the field types to their imports.
+var eventPkgs = map[string]string{
+	"uuid": uuidPkg,
+	"time": "time",
+	"mail": mailPkg,
+	"json": "encoding/json",
+	"sql":  "database/sql",
+}
+
+// typePkgRegexp matches the packages of a go type, e.g. uuid of []uuid.UUID.
+var typePkgRegexp = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.`)
+
+func eventCode(entity generator.Entity, primaryKey, modulePath string) (string, error) {
+	v := receiverName(entity.Struct)
+	name := generator.SnakeCase(entity.Struct)
+	words := strings.ReplaceAll(name, "_", " ")
+
+	imports := []string{"fmt", modulePath + "/pkg/outbox"}
+	seen := map[string]bool{}
+	fields := strings.Builder{}
+	values := strings.Builder{}
+	for _, field := range entity.Fields {
+		if field.Sensitive {
+			continue
+		}
+		for _, match := range typePkgRegexp.FindAllStringSubmatch(field.Type, -1) {
+			pkg, ok := eventPkgs[match[1]]
+			if !ok {
+				return "", fmt.Errorf("%s: unknown package of the type %s", field.Name, field.Type)
+			}
+			if !seen[pkg] {
+				seen[pkg] = true
+				imports = append(imports, pkg)
+			}
+		}
+
+		typ := field.Type
+		if field.Nullable {
+			typ = "*" + typ
+		}
+		fmt.Fprintf(&fields, "\t%s %s `json:\"%s\"`\n", field.Name, typ, field.Column)
+		fmt.Fprintf(&values, "\t\t%s: %s.%s,\n", field.Name, v, field.Name)
+	}
+
+	body := fmt.Sprintf(`// %[2]sEvent is the %[3]s of the events, without its sensitive fields.
+type %[2]sEvent struct {
+%[7]s}
+
+// new%[2]sEvent returns the %[3]s of the events.
+func new%[2]sEvent(%[5]s %[2]s) %[2]sEvent {
+	return %[2]sEvent{
+%[8]s	}
+}
+
+// list of the event types of the %[3]s
+const (
+	EventCreated = "%[1]s.created"
+	EventUpdated = "%[1]s.updated"
+	EventDeleted = "%[1]s.deleted"
+)
+
+// %[2]sCreated is the event of a created %[3]s.
+type %[2]sCreated struct {
+	%[2]s %[2]sEvent `+"`json:\"%[4]s\"`"+`
+}
+
+// %[2]sUpdated is the event of an updated %[3]s with its values after the update.
+type %[2]sUpdated struct {
+	%[2]s %[2]sEvent `+"`json:\"%[4]s\"`"+`
+}
+
+// %[2]sDeleted is the event of a deleted %[3]s.
+type %[2]sDeleted struct {
+	ID string `+"`json:\"id\"`"+`
+}
+
+// newCreatedEvent returns the outbox event of the created %[3]s.
+func newCreatedEvent(%[5]s %[2]s) (outbox.Event, error) {
+	return outbox.NewEvent(EventCreated, %[1]q, fmt.Sprint(%[5]s.%[6]s), %[2]sCreated{%[2]s: new%[2]sEvent(%[5]s)})
+}
+
+// newUpdatedEvent returns the outbox event of the updated %[3]s.
+func newUpdatedEvent(%[5]s %[2]s) (outbox.Event, error) {
+	return outbox.NewEvent(EventUpdated, %[1]q, fmt.Sprint(%[5]s.%[6]s), %[2]sUpdated{%[2]s: new%[2]sEvent(%[5]s)})
+}
+
+// newDeletedEvent returns the outbox event of the deleted %[3]s.
+func newDeletedEvent(id string) (outbox.Event, error) {
+	return outbox.NewEvent(EventDeleted, %[1]q, id, %[2]sDeleted{ID: id})
+}
+`, entity.Name, entity.Struct, words, name, v, primaryKey, fields.String(), values.String())
+
+	return goFile(entity.Name, imports, body)
+}
+
+func eventTest(entity generator.Entity, modulePath string) (string, error) {
+	sensitive := []string{}
+	for _, field := range entity.Fields {
+		if field.Sensitive {
+			sensitive = append(sensitive, fmt.Sprintf("%q", field.Column))
+		}
+	}
+
+	body := fmt.Sprintf(`func TestEvents(t *testing.T) {
+	// the sensitive fields are left out of the payloads
+	sensitive := []string{%[3]s}
+
+	tests := []struct {
+		name  string
+		event func() (outbox.Event, error)
+		typ   string
+	}{
+		{"created", func() (outbox.Event, error) { return newCreatedEvent(%[2]s{}) }, EventCreated},
+		{"updated", func() (outbox.Event, error) { return newUpdatedEvent(%[2]s{}) }, EventUpdated},
+		{"deleted", func() (outbox.Event, error) { return newDeletedEvent("id") }, EventDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := tt.event()
+			if err != nil {
+				t.Fatalf("event: %%v", err)
+			}
+			if event.Type != tt.typ || event.Aggregate != %[1]q {
+				t.Errorf("event is %%s of %%s, want %%s of %%s", event.Type, event.Aggregate, tt.typ, %[1]q)
+			}
+			if event.ID == "" || event.OccurredAt.IsZero() {
+				t.Errorf("event has no id or time: %%+v", event)
+			}
+			if !json.Valid(event.Payload) {
+				t.Errorf("payload is not json: %%s", event.Payload)
+			}
+			for _, column := range sensitive {
+				if strings.Contains(string(event.Payload), strconv.Quote(column)) {
+					t.Errorf("payload has the sensitive %%s: %%s", column, event.Payload)
+				}
+			}
+		})
+	}
+}
+`, entity.Name, entity.Struct, strings.Join(sensitive, ", "))
+
+	return goFile(entity.Name, []string{"encoding/json", "strconv", "strings", "testing", modulePath + "/pkg/outbox"}, body)
+}
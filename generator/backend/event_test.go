@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+func TestEventFiles(t *testing.T) {
+	user := generator.Entity{
+		Name:   "user",
+		Events: true,
+		Fields: []generator.Field{
+			{Name: "ID", Type: "uuid.UUID"},
+			{Name: "Email", Type: "mail.Address"},
+			{Name: "PasswordHash", Type: "[]byte"},
+			{Name: "APIKey", Type: "string", Sensitive: true},
+			{Name: "LastLogin", Type: "time.Time", Nullable: true},
+		},
+	}
+	user.SetDefaults()
+
+	orderItem := generator.Entity{
+		Name:   "orderitem",
+		Table:  "order_items",
+		Events: true,
+		Fields: []generator.Field{{Name: "ID", Type: "int64"}, {Name: "Quantity", Type: "int"}},
+	}
+	orderItem.SetDefaults()
+
+	unknown := generator.Entity{
+		Name:   "price",
+		Events: true,
+		Fields: []generator.Field{{Name: "ID", Type: "int64"}, {Name: "Amount", Type: "decimal.Decimal"}},
+	}
+	unknown.SetDefaults()
+
+	testCases := map[string]struct {
+		entity   generator.Entity
+		wantCode []string
+		wantNot  []string
+		wantTest []string
+		wantErr  string
+	}{
+		"sensitive fields": {
+			entity: user,
+			wantCode: []string{
+				"type UserEvent struct {",
+				"ID        uuid.UUID    `json:\"id\"`",
+				"Email     mail.Address `json:\"email\"`",
+				"LastLogin *time.Time   `json:\"last_login\"`",
+				"LastLogin: u.LastLogin,",
+				"User UserEvent `json:\"user\"`",
+				"UserCreated{User: newUserEvent(u)}",
+				"\"github.com/google/uuid\"",
+				"\"net/mail\"",
+				"\"time\"",
+			},
+			wantNot:  []string{"PasswordHash", "APIKey"},
+			wantTest: []string{`sensitive := []string{"password_hash", "api_key"}`},
+		},
+		"multi-word entity": {
+			entity: orderItem,
+			wantCode: []string{
+				`EventCreated = "orderitem.created"`,
+				"OrderItem OrderItemEvent `json:\"order_item\"`",
+				"func newOrderItemEvent(oi OrderItem) OrderItemEvent {",
+				"outbox.NewEvent(EventUpdated, \"orderitem\", fmt.Sprint(oi.ID), OrderItemUpdated{OrderItem: newOrderItemEvent(oi)})",
+			},
+			wantNot:  []string{"\"time\"", "uuid"},
+			wantTest: []string{"sensitive := []string{}"},
+		},
+		"unknown package": {
+			entity:  unknown,
+			wantErr: "unknown package of the type decimal.Decimal",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			files, err := EventFiles(tc.entity, "example.com/shop")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("EventFiles() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EventFiles: %v", err)
+			}
+			assertPaths(t, files, []string{
+				"business/" + tc.entity.Name + "/event.go",
+				"business/" + tc.entity.Name + "/event_test.go",
+			})
+
+			for _, want := range tc.wantCode {
+				if !strings.Contains(files[0].Content, want) {
+					t.Errorf("event.go has no %q:\n%s", want, files[0].Content)
+				}
+			}
+			for _, not := range tc.wantNot {
+				if strings.Contains(files[0].Content, not) {
+					t.Errorf("event.go has %q:\n%s", not, files[0].Content)
+				}
+			}
+			for _, want := range tc.wantTest {
+				if !strings.Contains(files[1].Content, want) {
+					t.Errorf("event_test.go has no %q:\n%s", want, files[1].Content)
+				}
+			}
+		})
+	}
+}
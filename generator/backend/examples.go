@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"github.com/go-flexi/codegenerator/generator/prompt"
+)
+
+// BusinessDir is the directory of the entity packages of the project.
+const BusinessDir = "business"
+
+// exampleFiles are the files of an entity package used as the few-shot examples.
+var exampleFiles = []string{"model.go", "filter.go", "order.go", "core.go"}
+
+// ExampleMarker is the file which marks a hand-approved entity package as the few-shot
+// examples, e.g. business/user/.codegen-example. The generated packages never have it.
+const ExampleMarker = ".codegen-example"
+
+// FindExamples returns the files of a hand-approved entity package as the few-shot examples
+// of the system prompt: the entityDir, or when it is empty the first package of the business
+// directory marked with ExampleMarker. No examples are returned when no package is marked,
+// the built-in samples are used then.
+func FindExamples(w *generator.Writer, entityDir string) ([]prompt.Example, error) {
+	if entityDir == "" {
+		dir, err := markedExampleDir(w)
+		if err != nil {
+			return nil, err
+		}
+		if dir == "" {
+			return nil, nil
+		}
+		entityDir = dir
+	}
+
+	examples, err := readExamples(w, entityDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(examples) != len(exampleFiles) {
+		return nil, fmt.Errorf("%s needs %s", entityDir, strings.Join(exampleFiles, ", "))
+	}
+	return examples, nil
+}
+
+// markedExampleDir returns the first entity package of the business directory which has the
+// ExampleMarker, or an empty string when none has it.
+func markedExampleDir(w *generator.Writer) (string, error) {
+	names, err := w.List(BusinessDir)
+	if err != nil {
+		return "", fmt.Errorf("List: %w", err)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		files, err := w.List(path.Join(BusinessDir, name))
+		if err != nil {
+			// skip the files of the business directory
+			continue
+		}
+		for _, file := range files {
+			if file == ExampleMarker {
+				return path.Join(BusinessDir, name), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// readExamples reads the example files which exist in the directory.
+func readExamples(w *generator.Writer, dir string) ([]prompt.Example, error) {
+	examples := []prompt.Example{}
+	for _, name := range exampleFiles {
+		filePath := path.Join(dir, name)
+		code, err := w.Read(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("Read: %w", err)
+		}
+		if code == "" {
+			continue
+		}
+		examples = append(examples, prompt.Example{Path: filePath, Code: strings.TrimSpace(code)})
+	}
+	return examples, nil
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"path"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+func TestFindExamples(t *testing.T) {
+	testCases := map[string]struct {
+		files     []string
+		entityDir string
+		wantDir   string
+		wantErr   bool
+	}{
+		"no marked package": {
+			files: []string{"business/address/model.go", "business/address/filter.go", "business/address/order.go", "business/address/core.go"},
+		},
+		"marked package": {
+			files: []string{
+				"business/address/model.go", "business/address/filter.go", "business/address/order.go", "business/address/core.go",
+				"business/user/model.go", "business/user/filter.go", "business/user/order.go", "business/user/core.go",
+				"business/user/" + ExampleMarker,
+			},
+			wantDir: "business/user",
+		},
+		"explicit directory": {
+			files:     []string{"business/user/model.go", "business/user/filter.go", "business/user/order.go", "business/user/core.go"},
+			entityDir: "business/user",
+			wantDir:   "business/user",
+		},
+		"incomplete marked package": {
+			files:   []string{"business/user/model.go", "business/user/" + ExampleMarker},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := generator.NewWriter(t.TempDir())
+			for _, file := range tc.files {
+				if _, err := w.Write(file, "package "+path.Base(path.Dir(file))+"\n"); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+
+			examples, err := FindExamples(w, tc.entityDir)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("FindExamples() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindExamples: %v", err)
+			}
+			if tc.wantDir == "" {
+				if len(examples) != 0 {
+					t.Errorf("FindExamples() = %d examples, want the built-in samples", len(examples))
+				}
+				return
+			}
+			if len(examples) != len(exampleFiles) {
+				t.Fatalf("FindExamples() = %d examples, want %d", len(examples), len(exampleFiles))
+			}
+			if want := path.Join(tc.wantDir, exampleFiles[0]); examples[0].Path != want {
+				t.Errorf("FindExamples() path = %s, want %s", examples[0].Path, want)
+			}
+		})
+	}
+}
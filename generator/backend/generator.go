@@ -2,42 +2,220 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path"
 
 	"github.com/go-flexi/codegenerator/generator"
+	"github.com/go-flexi/codegenerator/generator/prompt"
 	"github.com/go-flexi/codegenerator/openai"
 )
 
 // Generator generates backend code using OpenAI API.
 type Generator struct {
-	api         *openai.API
-	orgName     string
-	projectName string
+	api      *openai.API
+	prompts  *prompt.Library
+	examples []prompt.Example
+	module   generator.Module
 
 	messages generator.Messages
+	entity   string
+	// ir is the intermediate representation of the entity set with SetEntity.
+	ir    generator.Entity
+	files generator.Files
+	// w reads the existing files, their keep regions are sent with the calls.
+	w *generator.Writer
 }
 
-// NewGenerator creates a new Generator.
-func NewGenerator(api *openai.API, orgName, projectName string) *Generator {
-	return &Generator{
-		api:         api,
-		orgName:     orgName,
-		projectName: projectName,
-		messages:    generator.NewMessages(system),
+// ErrNoEntity is returned when a layer is generated before the model.
+var ErrNoEntity = errors.New("entity is unknown, generate the model first")
+
+// NewGenerator creates a new Generator with the system prompt of the library. The examples
+// replace the built-in samples of the system prompt, see FindExamples.
+func NewGenerator(api *openai.API, prompts *prompt.Library, module generator.Module, examples ...prompt.Example) (*Generator, error) {
+	g := Generator{
+		api:      api,
+		prompts:  prompts,
+		examples: examples,
+		module:   module,
+		files:    generator.Files{},
 	}
+
+	system, err := g.prompt("system")
+	if err != nil {
+		return nil, fmt.Errorf("prompt: %w", err)
+	}
+	g.messages = generator.NewMessages(system)
+
+	return &g, nil
 }
 
 // Generate generates backend code and this function needs to be called at the beginning.
 func (g *Generator) FirstCall(modelStruct string) (string, error) {
-	code, err := g.UserMessage(modelStruct + "\n" + "write the code for model.go")
+	// the entity of the model is only known before the call when it was set with SetEntity
+	keep := ""
+	if g.ir.Name != "" {
+		var err error
+		if keep, err = g.keepPrompt(path.Join(BusinessDir, g.ir.Name, "model.go")); err != nil {
+			return "", fmt.Errorf("keepPrompt: %w", err)
+		}
+	}
+
+	code, err := g.UserMessage(modelStruct + "\n" + "write the code for model.go" + keep)
 	if err != nil {
 		return "", fmt.Errorf("UserMessage: %w", err)
 	}
 
 	g.messages.AddAssistantMessage(code)
+	g.files["model.go"] = code
+
+	if entity, err := generator.PackageName(code); err == nil {
+		g.entity = entity
+	}
+
+	return code, nil
+}
+
+// AddFiles adds existing files of the project to the conversation. The entity is taken
+// from the package of model.go when the model was not generated.
+func (g *Generator) AddFiles(files ...generator.File) {
+	entity := g.files.AddFiles(&g.messages, "existing code of the project", files...)
+	if g.entity == "" {
+		g.entity = entity
+	}
+}
+
+// SetWriter sets the writer of the project. The keep regions of the existing files are sent
+// with the calls, so the model keeps them verbatim.
+func (g *Generator) SetWriter(w *generator.Writer) {
+	g.w = w
+}
+
+// SetEntity sets the intermediate representation of the entity, the code of the validation
+// rules, of the relations and of the features is generated with the core and the layers,
+// which call the permission checks of the policy.
+func (g *Generator) SetEntity(entity generator.Entity) {
+	g.ir = entity
+}
+
+// PackageCall generates a file of the entity package after the model, e.g. filter.go,
+// order.go or core.go.
+func (g *Generator) PackageCall(fileName string) (string, error) {
+	if g.entity == "" {
+		return "", ErrNoEntity
+	}
+
+	message, err := g.entityPrompt()
+	if err != nil {
+		return "", fmt.Errorf("entityPrompt: %w", err)
+	}
+
+	keep, err := g.keepPrompt(path.Join(BusinessDir, g.entity, fileName))
+	if err != nil {
+		return "", fmt.Errorf("keepPrompt: %w", err)
+	}
+
+	code, err := g.UserMessage(message + "write the code for " + fileName + keep)
+	if err != nil {
+		return "", fmt.Errorf("UserMessage: %w", err)
+	}
+
+	g.files[fileName] = code
+	return code, nil
+}
+
+// StoreCall generates the PostgreSQL implementation of the Store interface.
+func (g *Generator) StoreCall() (string, error) {
+	return g.layerCall("store", "", "store/%[1]sdb/%[1]sdb.go")
+}
+
+// HandlerCall generates the http handlers of the core for the framework.
+func (g *Generator) HandlerCall(framework Framework) (string, error) {
+	if err := framework.Validate(); err != nil {
+		return "", err
+	}
+	return g.layerCall("handler", framework.instruction(), "handler/%[1]shandler/%[1]shandler.go")
+}
+
+// CoreTestCall generates the unit tests of the core with a fake Store.
+func (g *Generator) CoreTestCall() (string, error) {
+	return g.layerCall("coretest", "", "business/%[1]s/core_test.go")
+}
+
+// StoreTestCall generates the integration tests of the store against an in-process SQLite
+// or an embedded PostgreSQL.
+func (g *Generator) StoreTestCall() (string, error) {
+	return g.layerCall("storetest", "", "store/%[1]sdb/%[1]sdb_test.go")
+}
+
+// layerCall generates the file of a layer with the prompt of the layer followed by the
+// instruction. The file name is formatted with the entity.
+func (g *Generator) layerCall(layer, instruction, fileName string) (string, error) {
+	if g.entity == "" {
+		return "", ErrNoEntity
+	}
+
+	message, err := g.prompt(layer)
+	if err != nil {
+		return "", fmt.Errorf("prompt: %w", err)
+	}
+	if instruction != "" {
+		message += "\n" + instruction
+	}
+
+	entityPrompt, err := g.entityPrompt()
+	if err != nil {
+		return "", fmt.Errorf("entityPrompt: %w", err)
+	}
+	message += "\n" + entityPrompt
+
+	fileName = fmt.Sprintf(fileName, g.entity)
+	keep, err := g.keepPrompt(fileName)
+	if err != nil {
+		return "", fmt.Errorf("keepPrompt: %w", err)
+	}
+
+	code, err := g.UserMessage(message + "write the code for " + fileName + keep)
+	if err != nil {
+		return "", fmt.Errorf("UserMessage: %w", err)
+	}
+
+	g.files[fileName] = code
 	return code, nil
 }
 
+// File returns the last generated code of the file.
+func (g *Generator) File(fileName string) (string, bool) {
+	return g.files.File(fileName)
+}
+
+// keepPrompt returns the instruction to keep the hand-written regions of the existing file
+// verbatim, or an empty string when the file has none. The writer restores them afterwards
+// when the model dropped them anyway.
+func (g *Generator) keepPrompt(filePath string) (string, error) {
+	if g.w == nil {
+		return "", nil
+	}
+
+	existing, err := g.w.Read(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Read: %w", err)
+	}
+	regions, err := generator.ExtractKeepRegions(existing)
+	if err != nil {
+		return "", fmt.Errorf("ExtractKeepRegions[%s]: %w", filePath, err)
+	}
+	if len(regions) == 0 {
+		return "", nil
+	}
+	return "\n" + generator.KeepPrompt(regions), nil
+}
+
+// Usage returns the tokens used by the conversation.
+func (g *Generator) Usage() openai.Usage {
+	return g.messages.Usage()
+}
+
 // UserMessage is used to receive user messages and generate backend code accordingly.
 func (g *Generator) UserMessage(message string) (string, error) {
 	if err := g.generateWithUserMessage(message); err != nil {
@@ -48,21 +226,69 @@ func (g *Generator) UserMessage(message string) (string, error) {
 
 func (g *Generator) generateWithUserMessage(message string) error {
 	g.messages.AddUserMessage(message)
-	if err := g.openaiCall(); err != nil {
-		return fmt.Errorf("openaiCall: %w", err)
+	if err := g.messages.Send(context.Background(), g.api); err != nil {
+		return fmt.Errorf("messages.Send: %w", err)
 	}
 	return nil
 }
 
-func (g *Generator) openaiCall() error {
-	response, err := g.api.Send(context.Background(), openai.DefaultConfig(), g.messages.GetMessages())
-	if err != nil {
-		return fmt.Errorf("api.Send: %w", err)
+// entityPrompt renders the validation prompt when the fields have validation rules, the
+// filters prompt when they have filter operators, the relations prompt when the entity has
+// relations, the permissions prompt when it has a policy, the features prompt when it has
+// features, the tenancy prompt when it is multi-tenant, the events prompt when it has events
+// and the pagination prompt when it uses cursor pagination, or returns an empty prompt.
+func (g *Generator) entityPrompt() (string, error) {
+	rules := false
+	for _, field := range g.ir.Fields {
+		rules = rules || field.Validate != nil
 	}
 
-	if len(response.Choices) > 0 {
-		g.messages.AddAssistantMessage(response.Choices[0].Message.Content)
+	prompts := []struct {
+		name    string
+		include bool
+	}{
+		{"validation", rules},
+		{"filters", g.ir.HasFilterOperators()},
+		{"relations", len(g.ir.Relations) > 0},
+		{"permissions", g.ir.Policy != nil},
+		{"features", g.ir.SoftDelete || g.ir.Audit || g.ir.Versioned},
+		{"tenancy", g.ir.MultiTenant},
+		{"events", g.ir.Events},
+		{"pagination", g.ir.Pagination == generator.CursorPagination},
 	}
 
-	return nil
+	message := ""
+	for _, p := range prompts {
+		if !p.include {
+			continue
+		}
+		text, err := g.prompt(p.name)
+		if err != nil {
+			return "", err
+		}
+		message += text
+	}
+	return message, nil
+}
+
+// prompt renders the prompt of the layer.
+func (g *Generator) prompt(layer string) (string, error) {
+	return g.prompts.Render(layer, prompt.Vars{
+		Module:      g.module.Path,
+		Org:         g.module.Org,
+		Project:     g.module.Project,
+		Entity:      g.entity,
+		Layer:       layer,
+		Examples:    g.examples,
+		Fields:      g.ir.Fields,
+		Filters:     g.ir.FilterFields(),
+		Relations:   g.ir.Relations,
+		Policy:      g.ir.Policy,
+		SoftDelete:  g.ir.SoftDelete,
+		Audit:       g.ir.Audit,
+		Versioned:   g.ir.Versioned,
+		MultiTenant: g.ir.MultiTenant,
+		Events:      g.ir.Events,
+		Pagination:  g.ir.Pagination,
+	})
 }
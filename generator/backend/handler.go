@@ -0,0 +1,33 @@
+package backend
+
+import "fmt"
+
+// Framework is the http framework of the generated handlers.
+type Framework string
+
+// list of frameworks
+const (
+	NetHTTP Framework = "net/http"
+	Chi     Framework = "chi"
+	Echo    Framework = "echo"
+)
+
+// Validate reports an unsupported framework.
+func (f Framework) Validate() error {
+	switch f {
+	case NetHTTP, Chi, Echo:
+		return nil
+	}
+	return fmt.Errorf("unknown framework %q, use %s, %s or %s", f, NetHTTP, Chi, Echo)
+}
+
+// instruction returns the instruction to adapt the sample to the framework.
+func (f Framework) instruction() string {
+	switch f {
+	case Chi:
+		return "Use github.com/go-chi/chi/v5 instead of net/http routing: register the routes on a chi.Router and read path parameters with chi.URLParam."
+	case Echo:
+		return "Use github.com/labstack/echo/v4 instead of net/http: handlers have the func(echo.Context) error signature, routes are registered on an *echo.Group and path parameters are read with c.Param."
+	}
+	return "Use net/http only."
+}
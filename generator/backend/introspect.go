@@ -0,0 +1,484 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// list of ddl patterns
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^create\s+(?:(?:global\s+|local\s+)?(?:temp|temporary|unlogged)\s+)?table\s+(?:if\s+not\s+exists\s+)?(\S+)\s*\((.*)\)`)
+	createIndexPattern = regexp.MustCompile(`(?is)^create\s+(?:unique\s+)?index\s+(?:concurrently\s+)?(?:if\s+not\s+exists\s+)?(?:\S+\s+)?on\s+(?:only\s+)?(\S+)(?:\s+using\s+\w+)?\s*\((.*)\)`)
+	primaryKeyPattern  = regexp.MustCompile(`(?is)^alter\s+table\s+(?:only\s+)?(\S+)\s+add\s+(?:constraint\s+\S+\s+)?primary\s+key\s*\((.*)\)`)
+	tableKeyPattern    = regexp.MustCompile(`(?is)^(?:constraint\s+\S+\s+)?(primary\s+key|unique)\s*\((.*)\)`)
+	foreignKeyPattern  = regexp.MustCompile(`(?is)^alter\s+table\s+(?:only\s+)?(\S+)\s+add\s+(?:constraint\s+\S+\s+)?(foreign\s+key.*)`)
+	tableFKPattern     = regexp.MustCompile(`(?is)^(?:constraint\s+\S+\s+)?foreign\s+key\s*\(([^)]*)\)\s*references\s+([^\s(]+)(.*)`)
+	referencesPattern  = regexp.MustCompile(`(?is)\breferences\s+([^\s(]+)(.*)`)
+	onDeletePattern    = regexp.MustCompile(`(?is)\bon\s+delete\s+(cascade|restrict|set\s+null)`)
+	tableConstraint    = regexp.MustCompile(`(?is)^(constraint|primary\s+key|unique|foreign\s+key|check|exclude)\b`)
+	typeSizePattern    = regexp.MustCompile(`\s*\([^)]*\)`)
+)
+
+// ParseSchema parses the CREATE TABLE, CREATE INDEX and ALTER TABLE ... PRIMARY KEY
+// statements of a pg_dump --schema-only or sqlite3 .schema dump, or of the sqlite_master
+// table of a SQLite database file. The other statements are ignored. The first column of an
+// index, or of a unique constraint, is indexed.
+func ParseSchema(ddl string) ([]Table, error) {
+	if strings.HasPrefix(ddl, sqliteHeader) {
+		var err error
+		if ddl, err = sqliteSchema([]byte(ddl)); err != nil {
+			return nil, fmt.Errorf("sqliteSchema: %w", err)
+		}
+	}
+
+	tables := []Table{}
+	index := map[string]int{}
+	for _, statement := range splitStatements(stripComments(ddl)) {
+		if m := createTablePattern.FindStringSubmatch(statement); m != nil {
+			table, err := parseCreateTable(tableName(m[1]), m[2])
+			if err != nil {
+				return nil, fmt.Errorf("parseCreateTable[%s]: %w", m[1], err)
+			}
+			index[table.Name] = len(tables)
+			tables = append(tables, table)
+			continue
+		}
+
+		if m := createIndexPattern.FindStringSubmatch(statement); m != nil {
+			if i, ok := index[tableName(m[1])]; ok {
+				tables[i].addIndex(splitList(m[2])[0])
+			}
+			continue
+		}
+
+		if m := primaryKeyPattern.FindStringSubmatch(statement); m != nil {
+			if i, ok := index[tableName(m[1])]; ok {
+				tables[i].setPrimaryKey(splitList(m[2]))
+			}
+			continue
+		}
+
+		if m := foreignKeyPattern.FindStringSubmatch(statement); m != nil {
+			if i, ok := index[tableName(m[1])]; ok {
+				tables[i].addForeignKey(m[2])
+			}
+		}
+	}
+
+	return tables, nil
+}
+
+// QueryTables reads the tables of the PostgreSQL schema from information_schema and
+// pg_indexes. All the tables of the schema are read when no names are given.
+func QueryTables(ctx context.Context, db *sql.DB, schema string, names ...string) ([]Table, error) {
+	const columnsQuery = `
+	SELECT c.table_name, c.column_name, c.data_type, c.udt_name, c.is_nullable = 'NO',
+		COALESCE(pk.is_primary, false)
+	FROM information_schema.columns c
+	JOIN information_schema.tables t
+		ON t.table_schema = c.table_schema AND t.table_name = c.table_name AND t.table_type = 'BASE TABLE'
+	LEFT JOIN (
+		SELECT kcu.table_name, kcu.column_name, true AS is_primary
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = $1 AND tc.constraint_type = 'PRIMARY KEY'
+	) pk ON pk.table_name = c.table_name AND pk.column_name = c.column_name
+	WHERE c.table_schema = $1
+	ORDER BY c.table_name, c.ordinal_position`
+
+	rows, err := db.QueryContext(ctx, columnsQuery, schema)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext[columns]: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	tables := []Table{}
+	index := map[string]int{}
+	for rows.Next() {
+		var tableName, dataType, udtName string
+		column := Column{}
+		if err := rows.Scan(&tableName, &column.Name, &dataType, &udtName, &column.NotNull, &column.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		if len(wanted) > 0 && !wanted[tableName] {
+			continue
+		}
+
+		column.Type = dataType
+		if dataType == "ARRAY" {
+			column.Type = strings.TrimPrefix(udtName, "_") + "[]"
+		} else if dataType == "USER-DEFINED" {
+			column.Type = udtName
+		}
+		column.Type = normalizeColumnType(column.Type)
+
+		i, ok := index[tableName]
+		if !ok {
+			i = len(tables)
+			index[tableName] = i
+			tables = append(tables, Table{Name: tableName, Columns: []Column{}, Indexes: []string{}})
+		}
+		tables[i].Columns = append(tables[i].Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	indexRows, err := db.QueryContext(ctx, `SELECT tablename, indexdef FROM pg_indexes WHERE schemaname = $1`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext[indexes]: %w", err)
+	}
+	defer indexRows.Close()
+
+	for indexRows.Next() {
+		var tableName, definition string
+		if err := indexRows.Scan(&tableName, &definition); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		i, ok := index[tableName]
+		if !ok {
+			continue
+		}
+		if m := createIndexPattern.FindStringSubmatch(definition); m != nil {
+			tables[i].addIndex(splitList(m[2])[0])
+		}
+	}
+	if err := indexRows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return tables, nil
+}
+
+// fieldTypes maps the normalized column types to go types, the reverse of columnTypes.
+var fieldTypes = map[string]string{
+	"uuid":                        "uuid.UUID",
+	"text":                        "string",
+	"varchar":                     "string",
+	"character varying":           "string",
+	"character":                   "string",
+	"char":                        "string",
+	"citext":                      "string",
+	"boolean":                     "bool",
+	"bool":                        "bool",
+	"bigint":                      "int64",
+	"int8":                        "int64",
+	"bigserial":                   "int64",
+	"integer":                     "int",
+	"int":                         "int",
+	"int4":                        "int",
+	"serial":                      "int",
+	"smallint":                    "int16",
+	"int2":                        "int16",
+	"double precision":            "float64",
+	"float8":                      "float64",
+	"float":                       "float64",
+	"numeric":                     "float64",
+	"decimal":                     "float64",
+	"real":                        "float32",
+	"float4":                      "float32",
+	"timestamptz":                 "time.Time",
+	"timestamp":                   "time.Time",
+	"timestamp with time zone":    "time.Time",
+	"timestamp without time zone": "time.Time",
+	"date":                        "time.Time",
+	"datetime":                    "time.Time",
+	"bytea":                       "[]byte",
+	"blob":                        "[]byte",
+	"text[]":                      "[]string",
+	"varchar[]":                   "[]string",
+	"jsonb":                       "map[string]any",
+	"json":                        "map[string]any",
+}
+
+// TableEntity returns the intermediate representation of an existing table. The indexed
+// columns are filterable, the primary key, the indexed and the time columns are sortable.
+// The foreign keys are belongs_to relations.
+func TableEntity(table Table) generator.Entity {
+	singular := generator.Singular(table.Name)
+	entity := generator.Entity{
+		Name:   strings.ReplaceAll(singular, "_", ""),
+		Struct: generator.CamelCase(singular),
+		Table:  table.Name,
+		Fields: []generator.Field{},
+	}
+
+	for _, column := range table.Columns {
+		field := generator.Field{
+			Name:       generator.CamelCase(column.Name),
+			Type:       FieldType(column),
+			Column:     column.Name,
+			Nullable:   !column.NotNull,
+			PrimaryKey: column.PrimaryKey,
+			Filterable: table.hasIndex(column.Name),
+			Immutable:  column.PrimaryKey || column.Name == "created_at",
+		}
+		field.Sortable = field.Filterable || field.PrimaryKey || field.Type == "time.Time"
+		field.Updatable = !field.Immutable && column.Name != "updated_at"
+		entity.Fields = append(entity.Fields, field)
+	}
+
+	for _, foreignKey := range table.ForeignKeys {
+		name := strings.TrimSuffix(foreignKey.Column, "_id")
+		entity.Relations = append(entity.Relations, generator.Relation{
+			Name:     generator.CamelCase(name),
+			Kind:     generator.BelongsTo,
+			Entity:   strings.ReplaceAll(generator.Singular(foreignKey.References), "_", ""),
+			Table:    foreignKey.References,
+			Field:    generator.CamelCase(foreignKey.Column),
+			OnDelete: foreignKey.OnDelete,
+		})
+	}
+
+	entity.SetDefaults()
+	return entity
+}
+
+// FieldType returns the go type of the column. Text columns named email are mail.Address,
+// like the model of the samples.
+func FieldType(column Column) string {
+	typ, ok := fieldTypes[column.Type]
+	if !ok {
+		typ = "string"
+	}
+	if typ == "string" && (column.Name == "email" || strings.HasSuffix(column.Name, "_email")) {
+		return "mail.Address"
+	}
+	return typ
+}
+
+func parseCreateTable(name, body string) (Table, error) {
+	table := Table{Name: name, Columns: []Column{}, Indexes: []string{}}
+	primaryKey := []string{}
+	for _, definition := range splitList(body) {
+		if tableConstraint.MatchString(definition) {
+			table.addForeignKey(definition)
+			if m := tableKeyPattern.FindStringSubmatch(definition); m != nil {
+				columns := splitList(m[2])
+				if strings.HasPrefix(strings.ToLower(m[1]), "primary") {
+					primaryKey = columns
+				} else {
+					table.addIndex(columns[0])
+				}
+			}
+			continue
+		}
+
+		fields := strings.Fields(definition)
+		if len(fields) < 2 {
+			return Table{}, fmt.Errorf("column %q has no type", definition)
+		}
+		column := Column{Name: identifier(fields[0])}
+
+		// the type runs until the first constraint keyword
+		typeWords := []string{}
+		rest := fields[1:]
+		for len(rest) > 0 && !isColumnConstraint(rest[0]) {
+			typeWords = append(typeWords, rest[0])
+			rest = rest[1:]
+		}
+		column.Type = normalizeColumnType(strings.Join(typeWords, " "))
+
+		constraints := strings.ToLower(strings.Join(rest, " "))
+		column.PrimaryKey = strings.Contains(constraints, "primary key")
+		column.NotNull = column.PrimaryKey || strings.Contains(constraints, "not null")
+		table.Columns = append(table.Columns, column)
+		if strings.Contains(constraints, "unique") {
+			table.addIndex(column.Name)
+		}
+		if m := referencesPattern.FindStringSubmatch(strings.Join(rest, " ")); m != nil {
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKey{
+				Column:     column.Name,
+				References: tableName(m[1]),
+				OnDelete:   onDelete(m[2]),
+			})
+		}
+	}
+
+	table.setPrimaryKey(primaryKey)
+	return table, nil
+}
+
+// addForeignKey adds the foreign key of a single column FOREIGN KEY constraint.
+func (t *Table) addForeignKey(constraint string) {
+	m := tableFKPattern.FindStringSubmatch(strings.TrimSpace(constraint))
+	if m == nil {
+		return
+	}
+	columns := splitList(m[1])
+	if len(columns) != 1 {
+		return
+	}
+	t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+		Column:     identifier(columns[0]),
+		References: tableName(m[2]),
+		OnDelete:   onDelete(m[3]),
+	})
+}
+
+// onDelete returns the on delete rule of the foreign key definition.
+func onDelete(definition string) string {
+	m := onDeletePattern.FindStringSubmatch(definition)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(strings.ToLower(m[1])), " ")
+}
+
+func (t *Table) addIndex(column string) {
+	column = identifier(column)
+	if t.hasIndex(column) {
+		return
+	}
+	// expression indexes and the primary key are not indexed columns
+	if c, ok := t.Column(column); !ok || c.PrimaryKey {
+		return
+	}
+	t.Indexes = append(t.Indexes, column)
+}
+
+func (t *Table) setPrimaryKey(columns []string) {
+	for _, name := range columns {
+		name = identifier(name)
+		for i := range t.Columns {
+			if t.Columns[i].Name == name {
+				t.Columns[i].PrimaryKey = true
+				t.Columns[i].NotNull = true
+			}
+		}
+		for i, index := range t.Indexes {
+			if index == name {
+				t.Indexes = append(t.Indexes[:i], t.Indexes[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// columnConstraints are the keywords which end the type of a column definition.
+var columnConstraints = map[string]bool{
+	"not": true, "null": true, "primary": true, "unique": true, "default": true, "references": true,
+	"check": true, "constraint": true, "collate": true, "generated": true, "autoincrement": true,
+}
+
+func isColumnConstraint(word string) bool {
+	return columnConstraints[strings.ToLower(word)]
+}
+
+// normalizeColumnType lower cases the type and drops the size, e.g. VARCHAR(255) is varchar.
+func normalizeColumnType(typ string) string {
+	typ = strings.ToLower(typeSizePattern.ReplaceAllString(typ, ""))
+	typ = strings.Join(strings.Fields(typ), " ")
+	if strings.HasPrefix(typ, "timestamp") && strings.Contains(typ, "with time zone") && !strings.Contains(typ, "without") {
+		return "timestamptz"
+	}
+	return typ
+}
+
+// tableName returns the table name without the schema and the quotes.
+func tableName(name string) string {
+	name = identifier(name)
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+	return identifier(name)
+}
+
+// identifier removes the quotes of the identifier.
+func identifier(name string) string {
+	return strings.Trim(strings.TrimSpace(name), "\"`[]")
+}
+
+// splitList splits the comma separated list outside of parentheses and quotes.
+func splitList(list string) []string {
+	items := []string{}
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(list[start:]))
+}
+
+// splitStatements splits the ddl on the semicolons outside of quotes.
+func splitStatements(ddl string) []string {
+	statements := []string{}
+	start := 0
+	var quote rune
+	for i, r := range ddl {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';':
+			if statement := strings.TrimSpace(ddl[start:i]); statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		}
+	}
+	if statement := strings.TrimSpace(ddl[start:]); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// stripComments removes the -- and /* */ comments of the ddl.
+func stripComments(ddl string) string {
+	buf := strings.Builder{}
+	var quote byte
+	for i := 0; i < len(ddl); i++ {
+		c := ddl[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '-' && i+1 < len(ddl) && ddl[i+1] == '-':
+			for i < len(ddl) && ddl[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(ddl) && ddl[i+1] == '*':
+			end := strings.Index(ddl[i+2:], "*/")
+			if end == -1 {
+				return buf.String()
+			}
+			i += end + 3
+			continue
+		}
+		if i < len(ddl) {
+			buf.WriteByte(ddl[i])
+		}
+	}
+	return buf.String()
+}
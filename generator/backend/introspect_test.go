@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// pgDump is the schema of a pg_dump --schema-only dump.
+const pgDump = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+
+CREATE TABLE public.users (
+    id uuid NOT NULL,
+    name character varying(120) NOT NULL,
+    email text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE TABLE public.orders (
+    id uuid NOT NULL,
+    user_id uuid,
+    total numeric(10,2) NOT NULL,
+    tags text[],
+    CONSTRAINT orders_total_check CHECK ((total >= (0)::numeric))
+);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE SET NULL;
+`
+
+func TestParseSchema(t *testing.T) {
+	sqlite, err := os.ReadFile("testdata/shop.sqlite")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %v", err)
+	}
+
+	testCases := map[string]struct {
+		ddl        string
+		wantTables int
+		want       []Table
+	}{
+		"pg_dump": {
+			ddl:        pgDump,
+			wantTables: 2,
+			want: []Table{
+				{
+					Name: "users",
+					Columns: []Column{
+						{Name: "id", Type: "uuid", NotNull: true, PrimaryKey: true},
+						{Name: "name", Type: "character varying", NotNull: true},
+						{Name: "email", Type: "text", NotNull: true},
+						{Name: "created_at", Type: "timestamptz", NotNull: true},
+					},
+					Indexes: []string{"email"},
+				},
+				{
+					Name: "orders",
+					Columns: []Column{
+						{Name: "id", Type: "uuid", NotNull: true, PrimaryKey: true},
+						{Name: "user_id", Type: "uuid"},
+						{Name: "total", Type: "numeric", NotNull: true},
+						{Name: "tags", Type: "text[]"},
+					},
+					Indexes:     []string{},
+					ForeignKeys: []ForeignKey{{Column: "user_id", References: "users", OnDelete: generator.SetNull}},
+				},
+			},
+		},
+		"sqlite .schema": {
+			ddl: `CREATE TABLE IF NOT EXISTS "tags" (id INTEGER PRIMARY KEY, label TEXT NOT NULL UNIQUE);
+CREATE INDEX tags_label ON tags (label);`,
+			wantTables: 1,
+			want: []Table{
+				{
+					Name: "tags",
+					Columns: []Column{
+						{Name: "id", Type: "integer", NotNull: true, PrimaryKey: true},
+						{Name: "label", Type: "text", NotNull: true},
+					},
+					Indexes: []string{"label"},
+				},
+			},
+		},
+		"sqlite database file": {
+			ddl:        string(sqlite),
+			wantTables: 11,
+			want: []Table{
+				{
+					Name: "users",
+					Columns: []Column{
+						{Name: "id", Type: "text", NotNull: true, PrimaryKey: true},
+						{Name: "name", Type: "text", NotNull: true},
+						{Name: "email", Type: "text", NotNull: true},
+					},
+					Indexes: []string{"email"},
+				},
+				{
+					Name: "orders",
+					Columns: []Column{
+						{Name: "id", Type: "text", NotNull: true, PrimaryKey: true},
+						{Name: "user_id", Type: "text", NotNull: true},
+						{Name: "total", Type: "integer", NotNull: true},
+						{Name: "note", Type: "text"},
+					},
+					Indexes:     []string{"total"},
+					ForeignKeys: []ForeignKey{{Column: "user_id", References: "users", OnDelete: generator.Cascade}},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tables, err := ParseSchema(tc.ddl)
+			if err != nil {
+				t.Fatalf("ParseSchema: %v", err)
+			}
+			if len(tables) != tc.wantTables {
+				t.Fatalf("ParseSchema() = %d tables, want %d", len(tables), tc.wantTables)
+			}
+			for i, want := range tc.want {
+				if !reflect.DeepEqual(tables[i], want) {
+					t.Errorf("tables[%d] =\n%+v\nwant\n%+v", i, tables[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSchema_corruptSQLite(t *testing.T) {
+	sqlite, err := os.ReadFile("testdata/shop.sqlite")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %v", err)
+	}
+
+	if _, err := ParseSchema(string(sqlite[:1024])); err == nil {
+		t.Errorf("ParseSchema() of a truncated file succeeded")
+	}
+}
+
+func TestTableEntity(t *testing.T) {
+	tables, err := ParseSchema(pgDump)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+
+	user := TableEntity(tables[0])
+	if user.Name != "user" || user.Struct != "User" || user.Table != "users" {
+		t.Errorf("TableEntity() = %s %s %s, want user User users", user.Name, user.Struct, user.Table)
+	}
+
+	testCases := map[string]struct {
+		entity generator.Entity
+		want   generator.Field
+	}{
+		"primary key": {
+			entity: user,
+			want: generator.Field{Name: "ID", Type: "uuid.UUID", Column: "id", PrimaryKey: true, Sortable: true,
+				Immutable: true},
+		},
+		"indexed email": {
+			entity: user,
+			want: generator.Field{Name: "Email", Type: "mail.Address", Column: "email", Filterable: true,
+				Sortable: true, Updatable: true},
+		},
+		"created at": {
+			entity: user,
+			want:   generator.Field{Name: "CreatedAt", Type: "time.Time", Column: "created_at", Sortable: true, Immutable: true},
+		},
+		"nullable array": {
+			entity: TableEntity(tables[1]),
+			want:   generator.Field{Name: "Tags", Type: "[]string", Column: "tags", Nullable: true, Updatable: true},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for _, field := range tc.entity.Fields {
+				if field.Name == tc.want.Name {
+					if !reflect.DeepEqual(field, tc.want) {
+						t.Errorf("field =\n%+v\nwant\n%+v", field, tc.want)
+					}
+					return
+				}
+			}
+			t.Errorf("TableEntity() has no field %s", tc.want.Name)
+		})
+	}
+
+	order := TableEntity(tables[1])
+	want := []generator.Relation{{Name: "User", Kind: generator.BelongsTo, Entity: "user", Table: "users", Field: "UserID",
+		OnDelete: generator.SetNull}}
+	if !reflect.DeepEqual(order.Relations, want) {
+		t.Errorf("Relations = %+v, want %+v", order.Relations, want)
+	}
+}
@@ -0,0 +1,551 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// MigrationFormat is the file format of the migrations.
+type MigrationFormat string
+
+// list of migration formats
+const (
+	GolangMigrate MigrationFormat = "golang-migrate"
+	Goose         MigrationFormat = "goose"
+)
+
+// Validate reports an unknown migration format.
+func (f MigrationFormat) Validate() error {
+	switch f {
+	case GolangMigrate, Goose:
+		return nil
+	}
+	return fmt.Errorf("unknown migration format %q, use %s or %s", f, GolangMigrate, Goose)
+}
+
+// list of migration directories
+const (
+	migrationsDir = "migrations"
+	snapshotsDir  = "migrations/.codegen"
+)
+
+// Table is the database table of an entity.
+type Table struct {
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	Indexes     []string     `json:"indexes"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+}
+
+// ForeignKey references the id of a table from a column.
+type ForeignKey struct {
+	Column     string `json:"column"`
+	References string `json:"references"`
+	OnDelete   string `json:"on_delete,omitempty"`
+}
+
+// Column is a column of a table.
+type Column struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	NotNull    bool   `json:"not_null"`
+	PrimaryKey bool   `json:"primary_key"`
+}
+
+// NewTable creates the table of the entity. The columns of the filterable fields are indexed,
+// and of the sortable fields too with cursor pagination, which seeks them, and the tenant of
+// the multi-tenant entities, which scopes every query. The foreign keys of the belongs_to
+// relations reference the tables of the related entities.
+func NewTable(entity generator.Entity) Table {
+	table := Table{
+		Name:    entity.Table,
+		Columns: []Column{},
+		Indexes: []string{},
+	}
+
+	for _, field := range entity.Fields {
+		column := Column{
+			Name:       field.Column,
+			Type:       columnType(field),
+			NotNull:    !field.Nullable,
+			PrimaryKey: field.PrimaryKey,
+		}
+		table.Columns = append(table.Columns, column)
+
+		keyset := field.Sortable && entity.Pagination == generator.CursorPagination
+		tenant := entity.MultiTenant && field.Name == generator.TenantIDField.Name
+		if (field.Filterable || keyset || tenant) && !column.PrimaryKey {
+			table.Indexes = append(table.Indexes, column.Name)
+		}
+	}
+
+	for _, relation := range entity.Relations {
+		if relation.Kind != generator.BelongsTo {
+			continue
+		}
+		if field, ok := entity.Field(relation.Field); ok {
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKey{
+				Column:     field.Column,
+				References: relation.Table,
+				OnDelete:   relation.OnDelete,
+			})
+		}
+	}
+
+	return table
+}
+
+// JoinTables creates the join tables of the many_to_many relations of the entity. The rows
+// are deleted with either of the entities. The column of the related entity is the singular
+// of its table, e.g. order_item_id for order_items.
+func JoinTables(entity generator.Entity) []Table {
+	primaryKey := Column{Type: "uuid"}
+	for _, field := range entity.Fields {
+		if field.PrimaryKey {
+			primaryKey.Type = columnType(field)
+		}
+	}
+
+	tables := []Table{}
+	for _, relation := range entity.Relations {
+		if relation.Kind != generator.ManyToMany {
+			continue
+		}
+
+		columns := []string{generator.SnakeCase(entity.Struct) + "_id", generator.Singular(relation.Table) + "_id"}
+		references := []string{entity.Table, relation.Table}
+		table := Table{Name: relation.JoinTable, Columns: []Column{}, Indexes: []string{}}
+		for i, name := range columns {
+			table.Columns = append(table.Columns, Column{Name: name, Type: primaryKey.Type, NotNull: true, PrimaryKey: true})
+			table.Indexes = append(table.Indexes, name)
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKey{Column: name, References: references[i], OnDelete: generator.Cascade})
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// Column returns the column by name.
+func (t Table) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+func (t Table) hasIndex(column string) bool {
+	for _, index := range t.Indexes {
+		if index == column {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Table) hasForeignKey(foreignKey ForeignKey) bool {
+	for _, fk := range t.ForeignKeys {
+		if fk == foreignKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Migrations returns the migration files of the entity package with the snapshot of its table.
+// The first migration creates the table, the following ones alter the table of the previous
+// snapshot. No files are returned when the table did not change.
+func Migrations(w *generator.Writer, entityDir string, format MigrationFormat) ([]generator.File, error) {
+	pkg, err := LoadPackage(w, entityDir)
+	if err != nil {
+		return nil, fmt.Errorf("LoadPackage: %w", err)
+	}
+
+	return EntityMigrations(w, pkg.Entity(), format)
+}
+
+// EntityMigrations returns the migration files of the table and of the join tables of the
+// entity with their snapshots, and of the outbox table when the entity has events.
+func EntityMigrations(w *generator.Writer, entity generator.Entity, format MigrationFormat) ([]generator.File, error) {
+	return EntitiesMigrations(w, []generator.Entity{entity}, format)
+}
+
+// EntitiesMigrations returns the migration files of the tables of the entities, see
+// EntityMigrations. The tables are migrated after the tables their foreign keys reference,
+// so the join tables follow both of their tables, and a table shared by the entities, e.g.
+// the outbox, is migrated once. A join table to an entity which is neither migrated with it
+// nor before it is an error.
+func EntitiesMigrations(w *generator.Writer, entities []generator.Entity, format MigrationFormat) ([]generator.File, error) {
+	tables := []Table{}
+	names := map[string]bool{}
+	joinTables := []Table{}
+	for _, entity := range entities {
+		joinTables = append(joinTables, JoinTables(entity)...)
+		entityTables := append([]Table{NewTable(entity)}, JoinTables(entity)...)
+		if entity.Events {
+			entityTables = append(entityTables, OutboxTable())
+		}
+		for _, table := range entityTables {
+			if !names[table.Name] {
+				names[table.Name] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	for _, table := range joinTables {
+		for _, foreignKey := range table.ForeignKeys {
+			if names[foreignKey.References] {
+				continue
+			}
+			snapshot, err := w.Read(path.Join(snapshotsDir, foreignKey.References+".json"))
+			if err != nil {
+				return nil, fmt.Errorf("Read: %w", err)
+			}
+			if snapshot == "" {
+				return nil, fmt.Errorf("join table %s references %s, which has no migration, migrate both entities together", table.Name, foreignKey.References)
+			}
+		}
+	}
+
+	tables, err := sortTables(tables)
+	if err != nil {
+		return nil, fmt.Errorf("sortTables: %w", err)
+	}
+
+	version, err := nextMigrationVersion(w)
+	if err != nil {
+		return nil, fmt.Errorf("nextMigrationVersion: %w", err)
+	}
+
+	files := []generator.File{}
+	for _, table := range tables {
+		tableFiles, err := tableMigrations(w, table, format, version)
+		if err != nil {
+			return nil, err
+		}
+		if len(tableFiles) > 0 {
+			files = append(files, tableFiles...)
+			version++
+		}
+	}
+	return files, nil
+}
+
+// sortTables orders the tables after the tables their foreign keys reference, keeping the
+// order of the tables otherwise. The references to the other tables, which exist already,
+// and to the table itself are ignored.
+func sortTables(tables []Table) ([]Table, error) {
+	index := map[string]int{}
+	for i, table := range tables {
+		index[table.Name] = i
+	}
+
+	sorted := make([]Table, 0, len(tables))
+	done := make([]bool, len(tables))
+	for len(sorted) < len(tables) {
+		next := -1
+		for i, table := range tables {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, foreignKey := range table.ForeignKeys {
+				if j, ok := index[foreignKey.References]; ok && j != i && !done[j] {
+					ready = false
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			cycle := []string{}
+			for i, table := range tables {
+				if !done[i] {
+					cycle = append(cycle, table.Name)
+				}
+			}
+			return nil, fmt.Errorf("the foreign keys of %s form a cycle", strings.Join(cycle, ", "))
+		}
+		done[next] = true
+		sorted = append(sorted, tables[next])
+	}
+	return sorted, nil
+}
+
+// TableMigrations returns the migration files of the table with its snapshot.
+func TableMigrations(w *generator.Writer, table Table, format MigrationFormat) ([]generator.File, error) {
+	version, err := nextMigrationVersion(w)
+	if err != nil {
+		return nil, fmt.Errorf("nextMigrationVersion: %w", err)
+	}
+
+	return tableMigrations(w, table, format, version)
+}
+
+func tableMigrations(w *generator.Writer, table Table, format MigrationFormat, version int) ([]generator.File, error) {
+	snapshotPath := path.Join(snapshotsDir, table.Name+".json")
+	snapshot, err := w.Read(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+
+	name := "create_" + table.Name + "_table"
+	up, down := createTable(table)
+	if snapshot != "" {
+		prev := Table{}
+		if err := json.Unmarshal([]byte(snapshot), &prev); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal[%s]: %w", snapshotPath, err)
+		}
+
+		name = "alter_" + table.Name + "_table"
+		up, down = alterTable(prev, table)
+		if up == "" {
+			return nil, nil
+		}
+	}
+
+	snapshotJSON, err := json.MarshalIndent(table, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+
+	files := migrationFiles(format, version, name, up, down)
+	return append(files, generator.File{Path: snapshotPath, Content: string(snapshotJSON) + "\n"}), nil
+}
+
+func migrationFiles(format MigrationFormat, version int, name, up, down string) []generator.File {
+	if format == Goose {
+		return []generator.File{{
+			Path:    path.Join(migrationsDir, fmt.Sprintf("%05d_%s.sql", version, name)),
+			Content: "-- +goose Up\n" + up + "\n-- +goose Down\n" + down,
+		}}
+	}
+
+	return []generator.File{
+		{Path: path.Join(migrationsDir, fmt.Sprintf("%06d_%s.up.sql", version, name)), Content: up},
+		{Path: path.Join(migrationsDir, fmt.Sprintf("%06d_%s.down.sql", version, name)), Content: down},
+	}
+}
+
+func nextMigrationVersion(w *generator.Writer) (int, error) {
+	names, err := w.List(migrationsDir)
+	if err != nil {
+		return 0, fmt.Errorf("List: %w", err)
+	}
+
+	version := 0
+	for _, name := range names {
+		digits := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsDigit(r) })
+		if digits <= 0 {
+			continue
+		}
+		if v, err := strconv.Atoi(name[:digits]); err == nil && v > version {
+			version = v
+		}
+	}
+	return version + 1, nil
+}
+
+func createTable(table Table) (string, string) {
+	up := bytes.Buffer{}
+	up.WriteString("CREATE TABLE " + table.Name + " (\n")
+
+	definitions := []string{}
+	primaryKeys := []string{}
+	for _, column := range table.Columns {
+		definitions = append(definitions, columnDefinition(column, false))
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, column.Name)
+		}
+	}
+	constraints := []string{}
+	if len(primaryKeys) > 0 {
+		constraints = append(constraints, "PRIMARY KEY ("+strings.Join(primaryKeys, ", ")+")")
+	}
+	for _, foreignKey := range table.ForeignKeys {
+		constraints = append(constraints, foreignKeyDefinition(table.Name, foreignKey))
+	}
+	up.WriteString("\t" + strings.Join(definitions, ",\n\t"))
+	if len(constraints) > 0 {
+		up.WriteString(",\n\n\t" + strings.Join(constraints, ",\n\t"))
+	}
+	up.WriteString("\n);\n")
+
+	for _, index := range table.Indexes {
+		up.WriteString("\n" + createIndex(table.Name, index))
+	}
+
+	return up.String(), "DROP TABLE IF EXISTS " + table.Name + ";\n"
+}
+
+func alterTable(prev, next Table) (string, string) {
+	ups := []string{}
+	downs := []string{}
+	alter := "ALTER TABLE " + next.Name + " "
+
+	for _, foreignKey := range prev.ForeignKeys {
+		if !next.hasForeignKey(foreignKey) {
+			ups = append(ups, alter+"DROP CONSTRAINT IF EXISTS "+foreignKeyName(next.Name, foreignKey.Column)+";\n")
+			downs = append(downs, alter+"ADD "+foreignKeyDefinition(next.Name, foreignKey)+";\n")
+		}
+	}
+
+	for _, index := range prev.Indexes {
+		if !next.hasIndex(index) {
+			ups = append(ups, dropIndex(next.Name, index))
+			downs = append(downs, createIndex(next.Name, index))
+		}
+	}
+
+	for _, column := range next.Columns {
+		prevColumn, ok := prev.Column(column.Name)
+		switch {
+		case !ok:
+			ups = append(ups, alter+"ADD COLUMN "+columnDefinition(column, true)+";\n")
+			downs = append(downs, alter+"DROP COLUMN "+column.Name+";\n")
+		case prevColumn.Type != column.Type:
+			ups = append(ups, alter+alterColumnType(column)+";\n")
+			downs = append(downs, alter+alterColumnType(prevColumn)+";\n")
+		}
+
+		if ok && prevColumn.NotNull != column.NotNull {
+			ups = append(ups, alter+alterColumnNotNull(column)+";\n")
+			downs = append(downs, alter+alterColumnNotNull(prevColumn)+";\n")
+		}
+	}
+
+	for _, column := range prev.Columns {
+		if _, ok := next.Column(column.Name); !ok {
+			ups = append(ups, alter+"DROP COLUMN "+column.Name+";\n")
+			downs = append(downs, alter+"ADD COLUMN "+columnDefinition(column, true)+";\n")
+		}
+	}
+
+	for _, index := range next.Indexes {
+		if !prev.hasIndex(index) {
+			ups = append(ups, createIndex(next.Name, index))
+			downs = append(downs, dropIndex(next.Name, index))
+		}
+	}
+
+	for _, foreignKey := range next.ForeignKeys {
+		if !prev.hasForeignKey(foreignKey) {
+			ups = append(ups, alter+"ADD "+foreignKeyDefinition(next.Name, foreignKey)+";\n")
+			downs = append(downs, alter+"DROP CONSTRAINT IF EXISTS "+foreignKeyName(next.Name, foreignKey.Column)+";\n")
+		}
+	}
+
+	for i, j := 0, len(downs)-1; i < j; i, j = i+1, j-1 {
+		downs[i], downs[j] = downs[j], downs[i]
+	}
+
+	return strings.Join(ups, ""), strings.Join(downs, "")
+}
+
+func columnDefinition(column Column, withDefault bool) string {
+	definition := column.Name + " " + column.Type
+	if column.NotNull {
+		definition += " NOT NULL"
+		if value, ok := columnDefaults[column.Type]; ok && withDefault {
+			definition += " DEFAULT " + value
+		}
+	}
+	return definition
+}
+
+func alterColumnType(column Column) string {
+	return fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", column.Name, column.Type, column.Name, column.Type)
+}
+
+func alterColumnNotNull(column Column) string {
+	if column.NotNull {
+		return "ALTER COLUMN " + column.Name + " SET NOT NULL"
+	}
+	return "ALTER COLUMN " + column.Name + " DROP NOT NULL"
+}
+
+func createIndex(table, column string) string {
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s);\n", indexName(table, column), table, column)
+}
+
+func dropIndex(table, column string) string {
+	return "DROP INDEX IF EXISTS " + indexName(table, column) + ";\n"
+}
+
+func indexName(table, column string) string {
+	return "idx_" + table + "_" + column
+}
+
+func foreignKeyDefinition(table string, foreignKey ForeignKey) string {
+	definition := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (id)",
+		foreignKeyName(table, foreignKey.Column), foreignKey.Column, foreignKey.References)
+	if foreignKey.OnDelete != "" {
+		definition += " ON DELETE " + strings.ToUpper(foreignKey.OnDelete)
+	}
+	return definition
+}
+
+func foreignKeyName(table, column string) string {
+	return "fk_" + table + "_" + column
+}
+
+// columnTypes maps go types to PostgreSQL column types.
+var columnTypes = map[string]string{
+	"uuid.UUID":    "uuid",
+	"time.Time":    "timestamptz",
+	"mail.Address": "text",
+	"string":       "text",
+	"bool":         "boolean",
+	"int":          "bigint",
+	"int64":        "bigint",
+	"uint":         "bigint",
+	"uint64":       "bigint",
+	"int32":        "integer",
+	"uint32":       "integer",
+	"int16":        "smallint",
+	"uint16":       "smallint",
+	"int8":         "smallint",
+	"uint8":        "smallint",
+	"float64":      "double precision",
+	"float32":      "real",
+	"[]byte":       "bytea",
+	"[]string":     "text[]",
+}
+
+// columnDefaults are the zero values used when a NOT NULL column is added to an existing table.
+var columnDefaults = map[string]string{
+	"uuid":             "'00000000-0000-0000-0000-000000000000'",
+	"timestamptz":      "now()",
+	"text":             "''",
+	"boolean":          "false",
+	"bigint":           "0",
+	"integer":          "0",
+	"smallint":         "0",
+	"double precision": "0",
+	"real":             "0",
+	"bytea":            "''",
+	"text[]":           "'{}'",
+	"jsonb":            "'null'",
+}
+
+func columnType(field generator.Field) string {
+	if columnType, ok := columnTypes[field.Type]; ok {
+		return columnType
+	}
+	if strings.HasPrefix(field.Type, "[]") || strings.HasPrefix(field.Type, "map[") {
+		return "jsonb"
+	}
+	return "text"
+}
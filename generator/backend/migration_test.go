@@ -0,0 +1,332 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+var usersTable = Table{
+	Name: "users",
+	Columns: []Column{
+		{Name: "id", Type: "uuid", NotNull: true, PrimaryKey: true},
+		{Name: "name", Type: "text", NotNull: true},
+	},
+	Indexes: []string{"name"},
+}
+
+func TestCreateTable(t *testing.T) {
+	testCases := map[string]struct {
+		table Table
+		want  string
+	}{
+		"primary key and index": {
+			table: usersTable,
+			want: "CREATE TABLE users (\n\tid uuid NOT NULL,\n\tname text NOT NULL,\n\n\tPRIMARY KEY (id)\n);\n" +
+				"\nCREATE INDEX idx_users_name ON users (name);\n",
+		},
+		"foreign key": {
+			table: Table{
+				Name:        "orders",
+				Columns:     []Column{{Name: "id", Type: "uuid", NotNull: true, PrimaryKey: true}, {Name: "user_id", Type: "uuid"}},
+				ForeignKeys: []ForeignKey{{Column: "user_id", References: "users", OnDelete: generator.SetNull}},
+			},
+			want: "CREATE TABLE orders (\n\tid uuid NOT NULL,\n\tuser_id uuid,\n\n\tPRIMARY KEY (id),\n" +
+				"\tCONSTRAINT fk_orders_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL\n);\n",
+		},
+		"no primary key": {
+			table: Table{Name: "logs", Columns: []Column{{Name: "line", Type: "text", NotNull: true}}},
+			want:  "CREATE TABLE logs (\n\tline text NOT NULL\n);\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			up, down := createTable(tc.table)
+			if up != tc.want {
+				t.Errorf("createTable() up =\n%s\nwant\n%s", up, tc.want)
+			}
+			if want := "DROP TABLE IF EXISTS " + tc.table.Name + ";\n"; down != want {
+				t.Errorf("createTable() down = %q, want %q", down, want)
+			}
+		})
+	}
+}
+
+func TestAlterTable(t *testing.T) {
+	testCases := map[string]struct {
+		next     func(t *Table)
+		wantUp   string
+		wantDown string
+	}{
+		"unchanged": {
+			next: func(t *Table) {},
+		},
+		"added column": {
+			next: func(t *Table) {
+				t.Columns = append(t.Columns, Column{Name: "enabled", Type: "boolean", NotNull: true})
+			},
+			wantUp:   "ALTER TABLE users ADD COLUMN enabled boolean NOT NULL DEFAULT false;\n",
+			wantDown: "ALTER TABLE users DROP COLUMN enabled;\n",
+		},
+		"dropped column and index": {
+			next: func(t *Table) {
+				t.Columns = t.Columns[:1]
+				t.Indexes = nil
+			},
+			wantUp:   "DROP INDEX IF EXISTS idx_users_name;\nALTER TABLE users DROP COLUMN name;\n",
+			wantDown: "ALTER TABLE users ADD COLUMN name text NOT NULL DEFAULT '';\nCREATE INDEX idx_users_name ON users (name);\n",
+		},
+		"changed type and null": {
+			next: func(t *Table) {
+				t.Columns[1] = Column{Name: "name", Type: "bigint"}
+			},
+			wantUp: "ALTER TABLE users ALTER COLUMN name TYPE bigint USING name::bigint;\n" +
+				"ALTER TABLE users ALTER COLUMN name DROP NOT NULL;\n",
+			wantDown: "ALTER TABLE users ALTER COLUMN name SET NOT NULL;\n" +
+				"ALTER TABLE users ALTER COLUMN name TYPE text USING name::text;\n",
+		},
+		"added foreign key": {
+			next: func(t *Table) {
+				t.ForeignKeys = []ForeignKey{{Column: "name", References: "names"}}
+			},
+			wantUp:   "ALTER TABLE users ADD CONSTRAINT fk_users_name FOREIGN KEY (name) REFERENCES names (id);\n",
+			wantDown: "ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_users_name;\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			next := usersTable
+			next.Columns = append([]Column{}, usersTable.Columns...)
+			next.Indexes = append([]string{}, usersTable.Indexes...)
+			tc.next(&next)
+
+			up, down := alterTable(usersTable, next)
+			if up != tc.wantUp {
+				t.Errorf("alterTable() up =\n%s\nwant\n%s", up, tc.wantUp)
+			}
+			if down != tc.wantDown {
+				t.Errorf("alterTable() down =\n%s\nwant\n%s", down, tc.wantDown)
+			}
+		})
+	}
+}
+
+func TestEntityMigrations(t *testing.T) {
+	entity := generator.Entity{
+		Name: "user",
+		Fields: []generator.Field{
+			{Name: "ID", Type: "uuid.UUID"},
+			{Name: "Name", Type: "string", Filterable: true},
+		},
+	}
+	entity.SetDefaults()
+
+	testCases := map[string]struct {
+		format    MigrationFormat
+		wantPaths []string
+	}{
+		"golang-migrate": {
+			format: GolangMigrate,
+			wantPaths: []string{
+				"migrations/000001_create_users_table.up.sql",
+				"migrations/000001_create_users_table.down.sql",
+				"migrations/.codegen/users.json",
+			},
+		},
+		"goose": {
+			format: Goose,
+			wantPaths: []string{
+				"migrations/00001_create_users_table.sql",
+				"migrations/.codegen/users.json",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := generator.NewWriter(t.TempDir())
+
+			files, err := EntityMigrations(w, entity, tc.format)
+			if err != nil {
+				t.Fatalf("EntityMigrations: %v", err)
+			}
+			assertPaths(t, files, tc.wantPaths)
+			for _, file := range files {
+				if _, err := w.Write(file.Path, file.Content); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+
+			// the snapshot is unchanged so there is no new migration
+			files, err = EntityMigrations(w, entity, tc.format)
+			if err != nil {
+				t.Fatalf("EntityMigrations: %v", err)
+			}
+			assertPaths(t, files, nil)
+
+			changed := entity
+			changed.Fields = append(append([]generator.Field{}, entity.Fields...), generator.Field{Name: "Age", Type: "int", Column: "age"})
+			files, err = EntityMigrations(w, changed, tc.format)
+			if err != nil {
+				t.Fatalf("EntityMigrations: %v", err)
+			}
+			if len(files) == 0 || !strings.Contains(files[0].Path, "2_alter_users_table") {
+				t.Errorf("EntityMigrations() = %v, want the second alter migration", files)
+			}
+		})
+	}
+}
+
+func TestMigrationFormat_Validate(t *testing.T) {
+	for _, format := range []MigrationFormat{GolangMigrate, Goose} {
+		if err := format.Validate(); err != nil {
+			t.Errorf("%s.Validate() = %v", format, err)
+		}
+	}
+	if err := MigrationFormat("gooose").Validate(); err == nil {
+		t.Errorf("Validate() of an unknown format succeeded")
+	}
+}
+
+func assertPaths(t *testing.T, files []generator.File, want []string) {
+	t.Helper()
+
+	if len(files) != len(want) {
+		t.Fatalf("files = %d, want %v", len(files), want)
+	}
+	for i, file := range files {
+		if file.Path != want[i] {
+			t.Errorf("files[%d] = %s, want %s", i, file.Path, want[i])
+		}
+	}
+}
+
+func TestEntitiesMigrations(t *testing.T) {
+	id := generator.Field{Name: "ID", Type: "uuid.UUID"}
+	user := generator.Entity{Name: "user", Fields: []generator.Field{id}}
+	order := generator.Entity{
+		Name:   "order",
+		Events: true,
+		Fields: []generator.Field{id, {Name: "UserID", Type: "uuid.UUID"}},
+		Relations: []generator.Relation{
+			{Name: "User", Kind: generator.BelongsTo, Entity: "user", Field: "UserID"},
+			{Name: "Tags", Kind: generator.ManyToMany, Entity: "tag"},
+		},
+	}
+	tag := generator.Entity{Name: "tag", Events: true, Fields: []generator.Field{id}}
+	category := generator.Entity{
+		Name:      "category",
+		Fields:    []generator.Field{id, {Name: "ParentID", Type: "uuid.UUID", Nullable: true}},
+		Relations: []generator.Relation{{Name: "Parent", Kind: generator.BelongsTo, Entity: "category", Field: "ParentID"}},
+	}
+	for _, e := range []*generator.Entity{&user, &order, &tag, &category} {
+		e.SetDefaults()
+	}
+
+	testCases := map[string]struct {
+		entities []generator.Entity
+		// existing are the tables migrated before
+		existing   []string
+		wantTables []string
+		wantErr    string
+	}{
+		"referenced tables first": {
+			entities:   []generator.Entity{order, tag, user},
+			wantTables: []string{"outbox_events", "tags", "users", "orders", "order_tags"},
+		},
+		"declared order kept": {
+			entities:   []generator.Entity{user, tag, order},
+			wantTables: []string{"users", "tags", "outbox_events", "orders", "order_tags"},
+		},
+		"self reference": {
+			entities:   []generator.Entity{category},
+			wantTables: []string{"categories"},
+		},
+		"reference to an existing table": {
+			entities:   []generator.Entity{order},
+			existing:   []string{"tags"},
+			wantTables: []string{"orders", "order_tags", "outbox_events"},
+		},
+		"join table without the related table": {
+			entities: []generator.Entity{order},
+			wantErr:  "join table order_tags references tags, which has no migration",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := generator.NewWriter(t.TempDir())
+			for _, table := range tc.existing {
+				if _, err := w.Write("migrations/.codegen/"+table+".json", "{}\n"); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+
+			files, err := EntitiesMigrations(w, tc.entities, Goose)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("EntitiesMigrations() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EntitiesMigrations: %v", err)
+			}
+
+			wantPaths := []string{}
+			for i, table := range tc.wantTables {
+				wantPaths = append(wantPaths,
+					fmt.Sprintf("migrations/%05d_create_%s_table.sql", i+1, table),
+					"migrations/.codegen/"+table+".json",
+				)
+			}
+			assertPaths(t, files, wantPaths)
+		})
+	}
+}
+
+func TestJoinTables(t *testing.T) {
+	order := generator.Entity{
+		Name:      "order",
+		Fields:    []generator.Field{{Name: "ID", Type: "uuid.UUID"}},
+		Relations: []generator.Relation{{Name: "Items", Kind: generator.ManyToMany, Entity: "OrderItem"}},
+	}
+	order.SetDefaults()
+
+	tables := JoinTables(order)
+	if len(tables) != 1 || tables[0].Name != "order_order_items" {
+		t.Fatalf("JoinTables() = %+v, want order_order_items", tables)
+	}
+	for i, want := range []ForeignKey{
+		{Column: "order_id", References: "orders", OnDelete: generator.Cascade},
+		{Column: "order_item_id", References: "order_items", OnDelete: generator.Cascade},
+	} {
+		if got := tables[0].ForeignKeys[i]; got != want {
+			t.Errorf("ForeignKeys[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestEntitiesMigrations_cycle(t *testing.T) {
+	a := generator.Entity{
+		Name:      "a",
+		Fields:    []generator.Field{{Name: "ID", Type: "uuid.UUID"}, {Name: "BID", Type: "uuid.UUID"}},
+		Relations: []generator.Relation{{Name: "B", Kind: generator.BelongsTo, Entity: "b", Field: "BID"}},
+	}
+	b := generator.Entity{
+		Name:      "b",
+		Fields:    []generator.Field{{Name: "ID", Type: "uuid.UUID"}, {Name: "AID", Type: "uuid.UUID"}},
+		Relations: []generator.Relation{{Name: "A", Kind: generator.BelongsTo, Entity: "a", Field: "AID"}},
+	}
+	a.SetDefaults()
+	b.SetDefaults()
+
+	_, err := EntitiesMigrations(generator.NewWriter(t.TempDir()), []generator.Entity{a, b}, Goose)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("EntitiesMigrations() error = %v, want the cycle", err)
+	}
+}
@@ -0,0 +1,524 @@
+package backend
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// Model is the parsed model.go of an entity.
+type Model struct {
+	Package string
+	Entity  Struct
+	New     Struct
+	Update  Struct
+}
+
+// Struct is a parsed struct type.
+type Struct struct {
+	Name   string
+	Fields []Field
+}
+
+// Field is a parsed struct field.
+type Field struct {
+	Name    string
+	Type    string
+	Pointer bool
+	// Tag is the codegen struct tag of the field, e.g. belongs_to=user,on_delete=cascade.
+	Tag string
+	// Validate is the validate struct tag of the field, e.g. required,max=64.
+	Validate string
+}
+
+// list of struct tag keys of the model
+const (
+	// RelationTag declares the relations of the model.
+	RelationTag = "codegen"
+	// ValidateTag declares the validation rules of the fields of the model.
+	ValidateTag = "validate"
+)
+
+// Field returns the field by name.
+func (s Struct) Field(name string) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// ParseModel parses the code of model.go. The entity is the struct named after the
+// package, NewX and UpdateX are the structs used to create and update it.
+func ParseModel(code string) (Model, error) {
+	file, err := parseFile(code)
+	if err != nil {
+		return Model{}, err
+	}
+
+	structs := fileStructs(file)
+	model := Model{Package: file.Name.Name}
+	for _, s := range structs {
+		if strings.EqualFold(s.Name, model.Package) {
+			model.Entity = s
+			break
+		}
+	}
+	if model.Entity.Name == "" {
+		for _, s := range structs {
+			if !strings.HasPrefix(s.Name, "New") && !strings.HasPrefix(s.Name, "Update") {
+				model.Entity = s
+				break
+			}
+		}
+	}
+	if model.Entity.Name == "" {
+		return Model{}, fmt.Errorf("package %s has no entity struct", model.Package)
+	}
+
+	for _, s := range structs {
+		switch s.Name {
+		case "New" + model.Entity.Name:
+			model.New = s
+		case "Update" + model.Entity.Name:
+			model.Update = s
+		}
+	}
+
+	return model, nil
+}
+
+// ParseModelStruct parses the model struct written before the model is generated, e.g. in
+// the TUI. The package is named after the entity struct when the code has no package clause.
+func ParseModelStruct(code string) (Model, error) {
+	code = generator.ExtractCode(code)
+	if _, err := generator.PackageName(code); err == nil {
+		return ParseModel(code)
+	}
+
+	model, err := ParseModel("package _\n\n" + code)
+	if err != nil {
+		return Model{}, err
+	}
+	model.Package = strings.ToLower(model.Entity.Name)
+	return model, nil
+}
+
+// Package is the parsed entity package.
+type Package struct {
+	Model  Model
+	Filter Struct
+	// OrderBy is the list of the order by constant values.
+	OrderBy []string
+	// Pagination is the cursor pagination when the Query of core.go takes a filter.CursorPage.
+	Pagination generator.Pagination
+	// Events is set when the package has the domain events of event.go.
+	Events bool
+}
+
+// LoadPackage parses model.go, the Filter of filter.go, the order by constants of order.go
+// and the pagination of core.go of the entity package, and looks for the events of event.go.
+// Only model.go is required.
+func LoadPackage(w *generator.Writer, entityDir string) (Package, error) {
+	modelCode, err := w.Read(path.Join(entityDir, "model.go"))
+	if err != nil {
+		return Package{}, fmt.Errorf("Read: %w", err)
+	}
+	if modelCode == "" {
+		return Package{}, fmt.Errorf("%s has no model.go", entityDir)
+	}
+
+	pkg := Package{}
+	if pkg.Model, err = ParseModel(modelCode); err != nil {
+		return Package{}, fmt.Errorf("ParseModel: %w", err)
+	}
+
+	filterCode, err := w.Read(path.Join(entityDir, "filter.go"))
+	if err != nil {
+		return Package{}, fmt.Errorf("Read: %w", err)
+	}
+	if filterCode != "" {
+		if pkg.Filter, err = ParseStruct(filterCode, "Filter"); err != nil {
+			return Package{}, fmt.Errorf("ParseStruct: %w", err)
+		}
+	}
+
+	orderCode, err := w.Read(path.Join(entityDir, "order.go"))
+	if err != nil {
+		return Package{}, fmt.Errorf("Read: %w", err)
+	}
+	if orderCode != "" {
+		if pkg.OrderBy, err = ParseOrderBy(orderCode); err != nil {
+			return Package{}, fmt.Errorf("ParseOrderBy: %w", err)
+		}
+	}
+
+	coreCode, err := w.Read(path.Join(entityDir, "core.go"))
+	if err != nil {
+		return Package{}, fmt.Errorf("Read: %w", err)
+	}
+	if strings.Contains(coreCode, "filter.CursorPage") {
+		pkg.Pagination = generator.CursorPagination
+	}
+
+	eventCode, err := w.Read(path.Join(entityDir, "event.go"))
+	if err != nil {
+		return Package{}, fmt.Errorf("Read: %w", err)
+	}
+	pkg.Events = eventCode != ""
+
+	for _, s := range []Struct{pkg.Model.Entity, pkg.Model.New, pkg.Model.Update} {
+		for _, f := range s.Fields {
+			if _, err := generator.ParseRules(f.Validate, f.Type); err != nil {
+				return Package{}, fmt.Errorf("%s.%s: ParseRules: %w", s.Name, f.Name, err)
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+// Rules returns the validation rules of the validate tag of the field of the entity, or of
+// the NewX field when the entity field has none, e.g. a password which is only hashed.
+func (m Model) Rules(name string) *generator.Rules {
+	f, ok := m.Entity.Field(name)
+	if !ok || f.Validate == "" {
+		f, _ = m.New.Field(name)
+	}
+	rules, _ := generator.ParseRules(f.Validate, f.Type)
+	return rules
+}
+
+// immutableFields are set when the entity is created and never change.
+var immutableFields = map[string]bool{"ID": true, "CreatedAt": true}
+
+// Entity returns the intermediate representation of the package. The fields of the Filter
+// are filterable, the fields of the order by constants are sortable and the fields of the
+// UpdateX struct are updatable. The filter operators are read from the names of the Filter
+// fields, see generator.FilterFieldName. The validation rules are declared with the validate
+// struct tag, see generator.ParseRules. The relations are declared with the codegen struct
+// tag: a foreign key field, e.g. UserID, has belongs_to=user and an optional on_delete rule,
+// a slice field has has_many=orderitem or many_to_many=tag and an optional join_table, it
+// is not a column.
+func (p Package) Entity() generator.Entity {
+	entity := generator.Entity{
+		Name:       p.Model.Package,
+		Struct:     p.Model.Entity.Name,
+		Table:      generator.Plural(generator.SnakeCase(p.Model.Entity.Name)),
+		Fields:     []generator.Field{},
+		Pagination: p.Pagination,
+		Events:     p.Events,
+	}
+
+	sortable := map[string]bool{}
+	for _, column := range p.OrderBy {
+		sortable[column] = true
+	}
+
+	for _, f := range p.Model.Entity.Fields {
+		relation, ok := fieldRelation(f)
+		if ok {
+			entity.Relations = append(entity.Relations, relation)
+			if relation.Kind != generator.BelongsTo {
+				continue
+			}
+		}
+
+		operators := filterOperators(p.Filter, f.Name)
+		_, updatable := p.Model.Update.Field(f.Name)
+		field := generator.Field{
+			Name:       f.Name,
+			Type:       f.Type,
+			Column:     generator.SnakeCase(f.Name),
+			Nullable:   f.Pointer,
+			PrimaryKey: f.Name == "ID",
+			Filterable: len(operators) > 0,
+			Updatable:  updatable && f.Name != "ID",
+			Immutable:  immutableFields[f.Name],
+			Validate:   p.Model.Rules(f.Name),
+		}
+		if len(operators) > 1 || (len(operators) == 1 && operators[0] != generator.EqualOperator) {
+			field.Operators = operators
+		}
+		field.Sortable = sortable[field.Column]
+		entity.Fields = append(entity.Fields, field)
+	}
+
+	entity.SetDefaults()
+	return entity
+}
+
+// coreFields are set by the core and the store, never by the clients.
+var coreFields = map[string]bool{
+	"CreatedAt":                   true,
+	"UpdatedAt":                   true,
+	generator.DeletedAtField.Name: true,
+	generator.CreatedByField.Name: true,
+	generator.UpdatedByField.Name: true,
+	generator.VersionField.Name:   true,
+	generator.TenantIDField.Name:  true,
+}
+
+// NewPackage returns the package of the entity, the reverse of Package.Entity. The NewX
+// struct has the fields which are not the primary key and not set by the core, the UpdateX
+// struct has the primary key, the Version of a versioned entity and the updatable fields,
+// and the Filter has the fields of the filter operators. The validation rules stay in the
+// entity.
+func NewPackage(e generator.Entity) Package {
+	model := Model{
+		Package: e.Name,
+		Entity:  Struct{Name: e.Struct, Fields: []Field{}},
+		New:     Struct{Name: "New" + e.Struct, Fields: []Field{}},
+		Update:  Struct{Name: "Update" + e.Struct, Fields: []Field{}},
+	}
+
+	for _, f := range e.Fields {
+		field := Field{Name: f.Name, Type: f.Type, Pointer: f.Nullable}
+		model.Entity.Fields = append(model.Entity.Fields, field)
+
+		switch {
+		case f.PrimaryKey, e.Versioned && f.Name == generator.VersionField.Name:
+			model.Update.Fields = append(model.Update.Fields, Field{Name: f.Name, Type: f.Type})
+		case f.Updatable:
+			model.Update.Fields = append(model.Update.Fields, Field{Name: f.Name, Type: f.Type, Pointer: true})
+		}
+		if !f.PrimaryKey && !coreFields[f.Name] {
+			model.New.Fields = append(model.New.Fields, field)
+		}
+	}
+
+	// the entity has no Filter when no field is filterable, like a package without filter.go
+	filter := Struct{}
+	for _, f := range e.FilterFields() {
+		typ, pointer := strings.CutPrefix(f.Type, "*")
+		filter.Name = "Filter"
+		filter.Fields = append(filter.Fields, Field{Name: f.Name, Type: typ, Pointer: pointer})
+	}
+
+	return Package{
+		Model:      model,
+		Filter:     filter,
+		OrderBy:    e.OrderBy(),
+		Pagination: e.Pagination,
+		Events:     e.Events,
+	}
+}
+
+// EntityPackage returns the package of the entity in the entity directory: the parsed package
+// when the directory has a model.go, see LoadPackage, or the package of the entity, see
+// NewPackage.
+func EntityPackage(w *generator.Writer, entityDir string, e generator.Entity) (Package, error) {
+	modelCode, err := w.Read(path.Join(entityDir, "model.go"))
+	if err != nil {
+		return Package{}, fmt.Errorf("Read: %w", err)
+	}
+	if modelCode == "" {
+		return NewPackage(e), nil
+	}
+
+	pkg, err := LoadPackage(w, entityDir)
+	if err != nil {
+		return Package{}, fmt.Errorf("LoadPackage: %w", err)
+	}
+	return pkg, nil
+}
+
+// withoutSensitive returns the package without the sensitive fields of the entity in its
+// structs, e.g. the PasswordHash of a User.
+func (p Package) withoutSensitive(e generator.Entity) Package {
+	sensitive := map[string]bool{}
+	for _, field := range e.Fields {
+		sensitive[field.Name] = field.Sensitive
+	}
+
+	p.Model.Entity = withoutFields(p.Model.Entity, sensitive)
+	p.Model.New = withoutFields(p.Model.New, sensitive)
+	p.Model.Update = withoutFields(p.Model.Update, sensitive)
+	p.Filter = withoutFields(p.Filter, sensitive)
+	return p
+}
+
+// withoutFields returns the struct without the fields set in names.
+func withoutFields(s Struct, names map[string]bool) Struct {
+	fields := []Field{}
+	for _, field := range s.Fields {
+		if !names[field.Name] {
+			fields = append(fields, field)
+		}
+	}
+	s.Fields = fields
+	return s
+}
+
+// filterOperators returns the operators of the Filter fields of the entity field, e.g. the
+// range operator of StartCreatedAt and EndCreatedAt.
+func filterOperators(filter Struct, name string) []generator.Operator {
+	operators := []generator.Operator{}
+	if _, ok := filter.Field(name); ok {
+		operators = append(operators, generator.EqualOperator)
+	}
+	_, start := filter.Field("Start" + name)
+	_, end := filter.Field("End" + name)
+	if start && end {
+		operators = append(operators, generator.RangeOperator)
+	}
+	for _, operator := range []generator.Operator{generator.InOperator, generator.ContainsOperator, generator.NullOperator} {
+		if _, ok := filter.Field(generator.FilterFieldName(name, operator)); ok {
+			operators = append(operators, operator)
+		}
+	}
+	return operators
+}
+
+// fieldRelation returns the relation declared by the codegen tag of the field.
+func fieldRelation(f Field) (generator.Relation, bool) {
+	if f.Tag == "" {
+		return generator.Relation{}, false
+	}
+
+	relation := generator.Relation{}
+	for _, option := range strings.Split(f.Tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case string(generator.BelongsTo), string(generator.HasMany), string(generator.ManyToMany):
+			relation.Kind = generator.RelationKind(key)
+			relation.Entity = value
+		case "table":
+			relation.Table = value
+		case "on_delete":
+			relation.OnDelete = strings.ReplaceAll(value, "_", " ")
+		case "join_table":
+			relation.JoinTable = value
+		}
+	}
+	if relation.Kind == "" {
+		return generator.Relation{}, false
+	}
+
+	relation.Name = f.Name
+	if relation.Kind == generator.BelongsTo {
+		relation.Name = strings.TrimSuffix(f.Name, "ID")
+		relation.Field = f.Name
+	}
+	return relation, true
+}
+
+// ParseOrderBy returns the values of the OrderBy constants of the code.
+func ParseOrderBy(code string) ([]string, error) {
+	file, err := parseFile(code)
+	if err != nil {
+		return nil, err
+	}
+
+	orderBy := []string{}
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.CONST {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, name := range valueSpec.Names {
+				if !strings.HasPrefix(name.Name, "OrderBy") || i >= len(valueSpec.Values) {
+					continue
+				}
+				lit, ok := valueSpec.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				value, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					return nil, fmt.Errorf("strconv.Unquote[%s]: %w", lit.Value, err)
+				}
+				orderBy = append(orderBy, value)
+			}
+		}
+	}
+
+	return orderBy, nil
+}
+
+// ParseStruct parses the struct with the name from the code.
+func ParseStruct(code, name string) (Struct, error) {
+	file, err := parseFile(code)
+	if err != nil {
+		return Struct{}, err
+	}
+
+	for _, s := range fileStructs(file) {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Struct{}, fmt.Errorf("struct %s not found", name)
+}
+
+func parseFile(code string) (*ast.File, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", generator.ExtractCode(code), parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parser.ParseFile: %w", err)
+	}
+	return file, nil
+}
+
+func fileStructs(file *ast.File) []Struct {
+	structs := []Struct{}
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			structs = append(structs, Struct{
+				Name:   typeSpec.Name.Name,
+				Fields: structFields(structType),
+			})
+		}
+	}
+	return structs
+}
+
+func structFields(structType *ast.StructType) []Field {
+	fields := []Field{}
+	for _, field := range structType.Fields.List {
+		typ := field.Type
+		star, pointer := typ.(*ast.StarExpr)
+		if pointer {
+			typ = star.X
+		}
+
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			if value, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(value)
+			}
+		}
+
+		for _, name := range field.Names {
+			fields = append(fields, Field{
+				Name:     name.Name,
+				Type:     types.ExprString(typ),
+				Pointer:  pointer,
+				Tag:      tag.Get(RelationTag),
+				Validate: tag.Get(ValidateTag),
+			})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+const userModel = `package user
+
+type User struct {
+	ID        uuid.UUID
+	Name      string ` + "`validate:\"required,max=64\"`" + `
+	Nickname  *string
+	CreatedAt time.Time
+}
+
+type NewUser struct {
+	Name     string
+	Nickname *string
+}
+
+type UpdateUser struct {
+	ID       uuid.UUID
+	Name     *string
+	Nickname *string
+}
+`
+
+func TestNewPackage(t *testing.T) {
+	model, err := ParseModel(userModel)
+	if err != nil {
+		t.Fatalf("ParseModel: %v", err)
+	}
+	pkg := Package{
+		Model:   model,
+		Filter:  Struct{Name: "Filter", Fields: []Field{{Name: "Name", Type: "string", Pointer: true}}},
+		OrderBy: []string{"id", "created_at"},
+	}
+
+	got := NewPackage(pkg.Entity())
+	for _, s := range []*Struct{&pkg.Model.Entity, &pkg.Model.New, &pkg.Model.Update} {
+		// the validation rules stay in the entity
+		for i := range s.Fields {
+			s.Fields[i].Validate = ""
+		}
+	}
+
+	testCases := map[string]struct {
+		got  interface{}
+		want interface{}
+	}{
+		"entity": {got: got.Model.Entity, want: pkg.Model.Entity},
+		"new":    {got: got.Model.New, want: pkg.Model.New},
+		"update": {got: got.Model.Update, want: pkg.Model.Update},
+		"filter": {got: got.Filter, want: pkg.Filter},
+		"order":  {got: got.OrderBy, want: pkg.OrderBy},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.got, tc.want) {
+				t.Errorf("NewPackage() =\n%+v\nwant\n%+v", tc.got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewPackage_noFilter(t *testing.T) {
+	entity := generator.NewEntity("tag")
+	entity.Fields = []generator.Field{{Name: "ID", Type: "int64"}}
+	entity.SetDefaults()
+
+	if pkg := NewPackage(entity); pkg.Filter.Name != "" {
+		t.Errorf("NewPackage() Filter = %+v, want none", pkg.Filter)
+	}
+}
+
+func TestParseModelStruct(t *testing.T) {
+	testCases := map[string]struct {
+		code        string
+		wantPackage string
+		wantStruct  string
+		wantErr     bool
+	}{
+		"package":            {code: userModel, wantPackage: "user", wantStruct: "User"},
+		"struct only":        {code: "type OrderItem struct {\n\tID int64\n}\n", wantPackage: "orderitem", wantStruct: "OrderItem"},
+		"fenced struct only": {code: "```go\ntype Tag struct {\n\tID int64\n}\n```", wantPackage: "tag", wantStruct: "Tag"},
+		"no struct":          {code: "func f() {}\n", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			model, err := ParseModelStruct(tc.code)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseModelStruct: no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseModelStruct: %v", err)
+			}
+			if model.Package != tc.wantPackage || model.Entity.Name != tc.wantStruct {
+				t.Errorf("ParseModelStruct() = %s.%s, want %s.%s", model.Package, model.Entity.Name, tc.wantPackage, tc.wantStruct)
+			}
+		})
+	}
+}
+
+func TestEntityPackage(t *testing.T) {
+	w := generator.NewWriter(t.TempDir())
+	if _, err := w.Write("business/user/model.go", userModel); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	entity := generator.Entity{Name: "user", Fields: []generator.Field{{Name: "ID", Type: "uuid.UUID"}, {Name: "Age", Type: "int"}}}
+	entity.SetDefaults()
+
+	testCases := map[string]struct {
+		entityDir string
+		wantNew   []string
+	}{
+		"parsed package":  {entityDir: "business/user", wantNew: []string{"Name", "Nickname"}},
+		"entity fallback": {entityDir: "business/tag", wantNew: []string{"Age"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pkg, err := EntityPackage(w, tc.entityDir, entity)
+			if err != nil {
+				t.Fatalf("EntityPackage: %v", err)
+			}
+			got := []string{}
+			for _, f := range pkg.Model.New.Fields {
+				got = append(got, f.Name)
+			}
+			if !reflect.DeepEqual(got, tc.wantNew) {
+				t.Errorf("NewUser fields = %v, want %v", got, tc.wantNew)
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"gopkg.in/yaml.v2"
+)
+
+// list of openapi paths
+const (
+	openAPIDir  = "api"
+	openAPIFile = "api/openapi.yaml"
+)
+
+// OpenAPI is an OpenAPI 3 document.
+type OpenAPI struct {
+	OpenAPI    string                 `yaml:"openapi"`
+	Info       OpenAPIInfo            `yaml:"info"`
+	Paths      map[string]OpenAPIPath `yaml:"paths"`
+	Components OpenAPIComponents      `yaml:"components"`
+}
+
+// OpenAPIInfo is the metadata of the document.
+type OpenAPIInfo struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+// OpenAPIPath maps the http methods of a path to their operations.
+type OpenAPIPath map[string]OpenAPIOperation
+
+// OpenAPIOperation is an operation of a path.
+type OpenAPIOperation struct {
+	OperationID string                     `yaml:"operationId"`
+	Tags        []string                   `yaml:"tags,omitempty"`
+	Parameters  []OpenAPIParameter         `yaml:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `yaml:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `yaml:"responses"`
+}
+
+// OpenAPIParameter is a path or query parameter.
+type OpenAPIParameter struct {
+	Name        string         `yaml:"name"`
+	In          string         `yaml:"in"`
+	Description string         `yaml:"description,omitempty"`
+	Required    bool           `yaml:"required,omitempty"`
+	Schema      *OpenAPISchema `yaml:"schema"`
+	// Explode is false for the comma separated values of the array parameters.
+	Explode *bool `yaml:"explode,omitempty"`
+}
+
+// OpenAPIRequestBody is the body of a request.
+type OpenAPIRequestBody struct {
+	Required bool                        `yaml:"required"`
+	Content  map[string]OpenAPIMediaType `yaml:"content"`
+}
+
+// OpenAPIResponse is a response, or a reference to a response of the components.
+type OpenAPIResponse struct {
+	Ref         string                      `yaml:"$ref,omitempty"`
+	Description string                      `yaml:"description,omitempty"`
+	Content     map[string]OpenAPIMediaType `yaml:"content,omitempty"`
+}
+
+// OpenAPIMediaType is the schema of a content type.
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `yaml:"schema"`
+}
+
+// OpenAPISchema is a schema, or a reference to a schema of the components.
+type OpenAPISchema struct {
+	Ref        string                    `yaml:"$ref,omitempty"`
+	Type       string                    `yaml:"type,omitempty"`
+	Format     string                    `yaml:"format,omitempty"`
+	Pattern    string                    `yaml:"pattern,omitempty"`
+	Nullable   bool                      `yaml:"nullable,omitempty"`
+	Enum       []string                  `yaml:"enum,omitempty"`
+	MinLength  *int                      `yaml:"minLength,omitempty"`
+	MaxLength  *int                      `yaml:"maxLength,omitempty"`
+	Minimum    *float64                  `yaml:"minimum,omitempty"`
+	Maximum    *float64                  `yaml:"maximum,omitempty"`
+	Default    interface{}               `yaml:"default,omitempty"`
+	Items      *OpenAPISchema            `yaml:"items,omitempty"`
+	Properties map[string]*OpenAPISchema `yaml:"properties,omitempty"`
+	Required   []string                  `yaml:"required,omitempty"`
+
+	AdditionalProperties *OpenAPISchema `yaml:"additionalProperties,omitempty"`
+}
+
+// OpenAPIComponents are the reusable schemas and responses.
+type OpenAPIComponents struct {
+	Schemas   map[string]*OpenAPISchema  `yaml:"schemas"`
+	Responses map[string]OpenAPIResponse `yaml:"responses"`
+}
+
+// openAPISchemas maps go types to openapi schemas.
+var openAPISchemas = map[string]OpenAPISchema{
+	"string":       {Type: "string"},
+	"bool":         {Type: "boolean"},
+	"int":          {Type: "integer", Format: "int64"},
+	"int64":        {Type: "integer", Format: "int64"},
+	"int32":        {Type: "integer", Format: "int32"},
+	"uint":         {Type: "integer", Format: "int64"},
+	"uint64":       {Type: "integer", Format: "int64"},
+	"uint32":       {Type: "integer", Format: "int32"},
+	"float64":      {Type: "number", Format: "double"},
+	"float32":      {Type: "number", Format: "float"},
+	"[]byte":       {Type: "string", Format: "byte"},
+	"[]string":     {Type: "array", Items: &OpenAPISchema{Type: "string"}},
+	"uuid.UUID":    {Type: "string", Format: "uuid"},
+	"time.Time":    {Type: "string", Format: "date-time"},
+	"mail.Address": {Type: "string", Format: "email"},
+}
+
+func openAPISchemaOf(field Field) *OpenAPISchema {
+	schema, ok := openAPISchemas[field.Type]
+	if !ok {
+		schema = OpenAPISchema{Type: "string"}
+	}
+	schema.Nullable = field.Pointer
+	return &schema
+}
+
+// NewOpenAPI returns the OpenAPI document of the entity with the endpoints of the generated
+// handlers. The schemas are the structs of the package of the entity, see EntityPackage,
+// which the handlers convert their requests to and respond with, without the sensitive
+// fields of the entity.
+func NewOpenAPI(pkg Package, e generator.Entity, title string) OpenAPI {
+	model := pkg.withoutSensitive(e).Model
+	entity := model.Entity.Name
+	resource := "/" + generator.Plural(generator.SnakeCase(entity))
+	tags := []string{entity}
+
+	doc := OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: title, Version: "1.0.0"},
+		Paths:   map[string]OpenAPIPath{},
+		Components: OpenAPIComponents{
+			Schemas: map[string]*OpenAPISchema{
+				entity:                        openAPIObject(model.Entity, e, false),
+				"Error":                       openAPIErrorSchema(),
+				"Query" + entity + "Response": openAPIQueryResponse(entity, e.Pagination),
+			},
+			Responses: openAPIErrorResponses(),
+		},
+	}
+
+	if model.New.Name != "" {
+		doc.Components.Schemas[model.New.Name] = openAPIObject(model.New, e, false)
+	}
+	if model.Update.Name != "" {
+		doc.Components.Schemas[model.Update.Name] = openAPIObject(model.Update, e, true)
+	}
+
+	// a versioned entity returns a conflict when the version of the update is stale
+	updateStatuses := []string{"400", "404", "500"}
+	if e.Versioned {
+		updateStatuses = append(updateStatuses, "409")
+	}
+
+	idParameter := OpenAPIParameter{Name: "id", In: "path", Required: true, Schema: &OpenAPISchema{Type: "string", Format: "uuid"}}
+
+	doc.Paths[resource] = OpenAPIPath{
+		"post": {
+			OperationID: "create" + entity,
+			Tags:        tags,
+			RequestBody: openAPIJSONBody(model.New.Name),
+			Responses:   withErrorResponses(map[string]OpenAPIResponse{"201": openAPIJSONResponse("Created", entity)}, "400", "500"),
+		},
+		"get": {
+			OperationID: "query" + generator.Plural(entity),
+			Tags:        tags,
+			Parameters:  openAPIQueryParameters(e),
+			Responses: withErrorResponses(map[string]OpenAPIResponse{
+				"200": openAPIJSONResponse("Page of "+generator.Plural(strings.ToLower(entity)), "Query"+entity+"Response"),
+			}, "400", "500"),
+		},
+	}
+	doc.Paths[resource+"/{id}"] = OpenAPIPath{
+		"get": {
+			OperationID: "get" + entity,
+			Tags:        tags,
+			Parameters:  []OpenAPIParameter{idParameter},
+			Responses:   withErrorResponses(map[string]OpenAPIResponse{"200": openAPIJSONResponse("Found", entity)}, "404", "500"),
+		},
+		"put": {
+			OperationID: "update" + entity,
+			Tags:        tags,
+			Parameters:  []OpenAPIParameter{idParameter},
+			RequestBody: openAPIJSONBody(model.Update.Name),
+			Responses:   withErrorResponses(map[string]OpenAPIResponse{"200": openAPIJSONResponse("Updated", entity)}, updateStatuses...),
+		},
+		"delete": {
+			OperationID: "delete" + entity,
+			Tags:        tags,
+			Parameters:  []OpenAPIParameter{idParameter},
+			Responses:   withErrorResponses(map[string]OpenAPIResponse{"204": {Description: "Deleted"}}, "404", "500"),
+		},
+	}
+
+	return doc
+}
+
+// MergeOpenAPI merges the paths and the components of the documents into the YAML of the
+// service document. Only the paths and the components the documents define are replaced, the
+// later documents win, and every other key of the service document, e.g. its servers, its
+// security or the descriptions of its hand-written paths, is kept as it is.
+func MergeOpenAPI(service []byte, docs ...OpenAPI) ([]byte, error) {
+	base := yaml.MapSlice{}
+	if err := yaml.Unmarshal(service, &base); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+
+	for _, doc := range docs {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("yaml.Marshal: %w", err)
+		}
+		merged := yaml.MapSlice{}
+		if err := yaml.Unmarshal(data, &merged); err != nil {
+			return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
+		}
+
+		for _, item := range merged {
+			key, _ := item.Key.(string)
+			switch key {
+			case "paths":
+				base = setMapItem(base, key, mergeMapSlice(mapItem(base, key), mapItem(merged, key)))
+			case "components":
+				components := mapItem(base, key)
+				for _, component := range mapItem(merged, key) {
+					name, _ := component.Key.(string)
+					components = setMapItem(components, name, mergeMapSlice(mapItem(components, name), mapItem(mapItem(merged, key), name)))
+				}
+				base = setMapItem(base, key, components)
+			default:
+				// the version and the info of the service document are kept
+				if _, ok := mapValue(base, key); !ok {
+					base = setMapItem(base, key, item.Value)
+				}
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(base)
+	if err != nil {
+		return nil, fmt.Errorf("yaml.Marshal: %w", err)
+	}
+	return data, nil
+}
+
+// OpenAPIFiles returns the document of the entity and the service document merged with it.
+func OpenAPIFiles(w *generator.Writer, pkg Package, entity generator.Entity, title string) ([]generator.File, error) {
+	doc := NewOpenAPI(pkg, entity, title)
+
+	existing, err := w.Read(openAPIFile)
+	if err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+
+	entityYAML, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("yaml.Marshal: %w", err)
+	}
+
+	serviceYAML, err := MergeOpenAPI([]byte(existing), doc)
+	if err != nil {
+		return nil, fmt.Errorf("MergeOpenAPI[%s]: %w", openAPIFile, err)
+	}
+
+	return []generator.File{
+		{Path: openAPIDir + "/" + entity.Name + ".yaml", Content: string(entityYAML)},
+		{Path: openAPIFile, Content: string(serviceYAML)},
+	}, nil
+}
+
+// mergeMapSlice sets the items of the map into the base, replacing the items with the same key.
+func mergeMapSlice(base, items yaml.MapSlice) yaml.MapSlice {
+	for _, item := range items {
+		key, _ := item.Key.(string)
+		base = setMapItem(base, key, item.Value)
+	}
+	return base
+}
+
+// mapItem returns the mapping of the key, or an empty mapping when the key is not a mapping.
+func mapItem(m yaml.MapSlice, key string) yaml.MapSlice {
+	value, _ := mapValue(m, key)
+	item, _ := value.(yaml.MapSlice)
+	return item
+}
+
+func mapValue(m yaml.MapSlice, key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+// setMapItem replaces the value of the key in place, or appends the key.
+func setMapItem(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range m {
+		if m[i].Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+// openAPIObject returns the schema of the struct with the validation rules of the entity.
+// The non-pointer fields are required, unless the struct is an update where every field but
+// the id in the path is nullable.
+func openAPIObject(s Struct, e generator.Entity, update bool) *OpenAPISchema {
+	schema := OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+	for _, field := range s.Fields {
+		if update && field.Name == "ID" {
+			continue
+		}
+
+		name := generator.SnakeCase(field.Name)
+		property := openAPISchemaOf(field)
+		if update {
+			property.Nullable = true
+		} else if !field.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+		if entityField, _ := e.Field(field.Name); entityField.Validate != nil {
+			openAPIRules(property, *entityField.Validate)
+		}
+		schema.Properties[name] = property
+	}
+	return &schema
+}
+
+// openAPIRules adds the validation rules to the schema of the property.
+func openAPIRules(property *OpenAPISchema, rules generator.Rules) {
+	property.Enum = rules.Enum
+	property.MinLength = rules.MinLength
+	property.MaxLength = rules.MaxLength
+	property.Minimum = rules.Min
+	property.Maximum = rules.Max
+	if rules.Pattern != "" {
+		property.Pattern = rules.Pattern
+	}
+	if rules.Email {
+		property.Format = "email"
+	}
+	if rules.Required && property.Type == "string" && property.MinLength == nil {
+		one := 1
+		property.MinLength = &one
+	}
+}
+
+func openAPIQueryParameters(e generator.Entity) []OpenAPIParameter {
+	parameters := []OpenAPIParameter{}
+	for _, field := range e.FilterFields() {
+		parameter := OpenAPIParameter{
+			Name:        field.Param,
+			In:          "query",
+			Description: openAPIFilterDescription(field),
+		}
+
+		if items := strings.TrimPrefix(field.Type, "[]"); items != field.Type {
+			explode := false
+			parameter.Schema = &OpenAPISchema{Type: "array", Items: openAPISchemaOf(Field{Type: items})}
+			parameter.Explode = &explode
+		} else {
+			parameter.Schema = openAPISchemaOf(Field{Type: strings.TrimPrefix(field.Type, "*")})
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	if orderBy := e.OrderBy(); len(orderBy) > 0 {
+		parameters = append(parameters, OpenAPIParameter{
+			Name:        "order_by",
+			In:          "query",
+			Description: "field and optional direction, e.g. " + orderBy[0] + ",DESC",
+			Schema: &OpenAPISchema{
+				Type:    "string",
+				Pattern: "^(" + strings.Join(orderBy, "|") + ")(,(ASC|DESC|asc|desc))?$",
+			},
+		})
+	}
+
+	one := 1.0
+	rows := OpenAPIParameter{Name: "rows", In: "query", Description: "rows per page", Schema: &OpenAPISchema{Type: "integer", Minimum: &one, Default: 10}}
+	if e.Pagination == generator.CursorPagination {
+		return append(parameters,
+			OpenAPIParameter{Name: "after", In: "query", Description: "next_cursor of the previous page", Schema: &OpenAPISchema{Type: "string"}},
+			OpenAPIParameter{Name: "before", In: "query", Description: "prev_cursor of the next page", Schema: &OpenAPISchema{Type: "string"}},
+			rows,
+		)
+	}
+	return append(parameters,
+		OpenAPIParameter{Name: "page", In: "query", Description: "page number", Schema: &OpenAPISchema{Type: "integer", Minimum: &one, Default: 1}},
+		rows,
+	)
+}
+
+// openAPIFilterDescription describes the filter of the operator of the query parameter.
+func openAPIFilterDescription(field generator.FilterField) string {
+	name := strings.ReplaceAll(field.Field.Column, "_", " ")
+	switch field.Operator {
+	case generator.RangeOperator:
+		if strings.HasPrefix(field.Name, "Start") {
+			return "filter by " + name + " from the value included"
+		}
+		return "filter by " + name + " until the value excluded"
+	case generator.InOperator:
+		return "filter by a comma separated list of " + generator.Plural(name)
+	case generator.ContainsOperator:
+		return "filter by " + name + " containing the value, ignoring the case"
+	case generator.NullOperator:
+		return "filter by " + name + " null when true, set when false"
+	}
+	return "filter by " + name
+}
+
+func openAPIQueryResponse(entity string, pagination generator.Pagination) *OpenAPISchema {
+	if pagination == generator.CursorPagination {
+		return &OpenAPISchema{
+			Type:     "object",
+			Required: []string{"items", "rows_per_page"},
+			Properties: map[string]*OpenAPISchema{
+				"items":         {Type: "array", Items: &OpenAPISchema{Ref: "#/components/schemas/" + entity}},
+				"next_cursor":   {Type: "string"},
+				"prev_cursor":   {Type: "string"},
+				"rows_per_page": {Type: "integer"},
+			},
+		}
+	}
+	return &OpenAPISchema{
+		Type:     "object",
+		Required: []string{"items", "page", "rows_per_page"},
+		Properties: map[string]*OpenAPISchema{
+			"items":         {Type: "array", Items: &OpenAPISchema{Ref: "#/components/schemas/" + entity}},
+			"page":          {Type: "integer"},
+			"rows_per_page": {Type: "integer"},
+		},
+	}
+}
+
+func openAPIErrorSchema() *OpenAPISchema {
+	return &OpenAPISchema{
+		Type:     "object",
+		Required: []string{"error"},
+		Properties: map[string]*OpenAPISchema{
+			"error":  {Type: "string"},
+			"fields": {Type: "object", AdditionalProperties: &OpenAPISchema{Type: "string"}},
+		},
+	}
+}
+
+// openAPIErrors maps the status codes to the names of the error responses.
+var openAPIErrors = map[string]string{
+	"400": "BadRequest",
+	"401": "Unauthenticated",
+	"403": "Forbidden",
+	"404": "NotFound",
+	"409": "Conflict",
+	"500": "InternalError",
+}
+
+func openAPIErrorResponses() map[string]OpenAPIResponse {
+	descriptions := map[string]string{
+		"BadRequest":      "Validation failed, fields holds the message of each invalid field",
+		"Unauthenticated": "Authentication required",
+		"Forbidden":       "Not allowed by the permission checks",
+		"NotFound":        "Not found",
+		"Conflict":        "The version of the update is stale",
+		"InternalError":   "Internal server error",
+	}
+
+	responses := map[string]OpenAPIResponse{}
+	for name, description := range descriptions {
+		responses[name] = openAPIJSONResponse(description, "Error")
+	}
+	return responses
+}
+
+// withErrorResponses adds the error responses of the statuses and of the permission checks
+// every operation runs.
+func withErrorResponses(responses map[string]OpenAPIResponse, statuses ...string) map[string]OpenAPIResponse {
+	for _, status := range append(statuses, "401", "403") {
+		responses[status] = OpenAPIResponse{Ref: "#/components/responses/" + openAPIErrors[status]}
+	}
+	return responses
+}
+
+func openAPIJSONResponse(description, schema string) OpenAPIResponse {
+	return OpenAPIResponse{
+		Description: description,
+		Content: map[string]OpenAPIMediaType{
+			"application/json": {Schema: &OpenAPISchema{Ref: "#/components/schemas/" + schema}},
+		},
+	}
+}
+
+func openAPIJSONBody(schema string) *OpenAPIRequestBody {
+	if schema == "" {
+		return nil
+	}
+	return &OpenAPIRequestBody{
+		Required: true,
+		Content: map[string]OpenAPIMediaType{
+			"application/json": {Schema: &OpenAPISchema{Ref: "#/components/schemas/" + schema}},
+		},
+	}
+}
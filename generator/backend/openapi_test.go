@@ -0,0 +1,234 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeOpenAPI(t *testing.T) {
+	doc := OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "generated", Version: "0.1.0"},
+		Paths: map[string]OpenAPIPath{
+			"/users": {"get": {OperationID: "queryUser", Responses: map[string]OpenAPIResponse{"200": {Description: "Users"}}}},
+		},
+		Components: OpenAPIComponents{
+			Schemas:   map[string]*OpenAPISchema{"User": {Type: "object"}},
+			Responses: map[string]OpenAPIResponse{"NotFound": {Description: "Not found"}},
+		},
+	}
+
+	testCases := map[string]struct {
+		service   string
+		want      []string
+		wantNot   []string
+		wantTitle string
+	}{
+		"new service document": {
+			service:   "",
+			want:      []string{"openapi: 3.0.3", "/users:", "operationId: queryUser", "User:", "NotFound:"},
+			wantTitle: "generated",
+		},
+		"hand-maintained keys are kept": {
+			service: `openapi: 3.0.3
+info:
+  title: shop
+  version: 2.0.0
+  description: the shop api
+servers:
+- url: https://api.example.com
+security:
+- bearer: []
+tags:
+- name: users
+  description: the users
+paths:
+  /health:
+    get:
+      operationId: health
+      description: liveness
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              example:
+                status: ok
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+  schemas:
+    Status:
+      type: string
+      enum: [ok, degraded]
+`,
+			want: []string{
+				"description: the shop api", "url: https://api.example.com", "bearer: []",
+				"description: the users", "/health:", "description: liveness", "status: ok",
+				"securitySchemes:", "scheme: bearer", "Status:", "- degraded",
+				"/users:", "operationId: queryUser", "User:", "NotFound:",
+			},
+			wantTitle: "shop",
+		},
+		"entity paths are replaced": {
+			service: `paths:
+  /users:
+    get:
+      operationId: oldQueryUser
+`,
+			want:      []string{"operationId: queryUser"},
+			wantNot:   []string{"oldQueryUser"},
+			wantTitle: "generated",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := MergeOpenAPI([]byte(tc.service), doc)
+			if err != nil {
+				t.Fatalf("MergeOpenAPI: %v", err)
+			}
+
+			for _, want := range tc.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("MergeOpenAPI() has no %q:\n%s", want, got)
+				}
+			}
+			for _, wantNot := range tc.wantNot {
+				if strings.Contains(string(got), wantNot) {
+					t.Errorf("MergeOpenAPI() has %q:\n%s", wantNot, got)
+				}
+			}
+
+			merged := OpenAPI{}
+			if err := yaml.Unmarshal(got, &merged); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if merged.Info.Title != tc.wantTitle {
+				t.Errorf("MergeOpenAPI() title = %s, want %s", merged.Info.Title, tc.wantTitle)
+			}
+		})
+	}
+}
+
+func TestMergeOpenAPI_invalid(t *testing.T) {
+	if _, err := MergeOpenAPI([]byte("paths: [")); err == nil {
+		t.Errorf("MergeOpenAPI() of invalid YAML succeeded")
+	}
+}
+
+func TestNewOpenAPI(t *testing.T) {
+	maxLength := 64
+	entity := generator.Entity{
+		Name:       "orderitem",
+		Table:      "order_items",
+		Versioned:  true,
+		Pagination: generator.CursorPagination,
+		Fields: []generator.Field{
+			{Name: "ID", Type: "uuid.UUID", Sortable: true},
+			{Name: "Note", Type: "string", Updatable: true, Validate: &generator.Rules{Required: true, MaxLength: &maxLength},
+				Operators: []generator.Operator{generator.ContainsOperator}},
+		},
+	}
+	entity.SetDefaults()
+
+	doc := NewOpenAPI(NewPackage(entity), entity, "shop")
+
+	testCases := map[string]struct {
+		got  interface{}
+		want interface{}
+	}{
+		"resource": {got: doc.Paths["/order_items"]["get"].OperationID, want: "queryOrderItems"},
+		"schemas": {
+			got:  len(doc.Components.Schemas),
+			want: 5,
+		},
+		"rules": {got: *doc.Components.Schemas["OrderItem"].Properties["note"].MaxLength, want: 64},
+		"filter": {
+			got:  doc.Paths["/order_items"]["get"].Parameters[0].Name,
+			want: "note_contains",
+		},
+		"cursor": {
+			got:  doc.Paths["/order_items"]["get"].Parameters[2].Name,
+			want: "after",
+		},
+		"conflict": {
+			got:  doc.Paths["/order_items/{id}"]["put"].Responses["409"].Ref != "",
+			want: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if tc.got != tc.want {
+				t.Errorf("NewOpenAPI() = %v, want %v", tc.got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewOpenAPI_package(t *testing.T) {
+	w := generator.NewWriter(t.TempDir())
+	for _, name := range []string{"model.go", "filter.go"} {
+		code, err := os.ReadFile(filepath.Join("testdata", "user", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write("business/user/"+name, string(code)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	pkg, err := LoadPackage(w, "business/user")
+	if err != nil {
+		t.Fatalf("LoadPackage: %v", err)
+	}
+
+	doc := NewOpenAPI(pkg, pkg.Entity(), "shop")
+
+	testCases := map[string]struct {
+		schema       string
+		wantProps    []string
+		wantRequired []string
+	}{
+		"response": {
+			schema:       "User",
+			wantProps:    []string{"created_at", "email", "enabled", "id", "name", "updated_at"},
+			wantRequired: []string{"id", "name", "email", "enabled", "created_at", "updated_at"},
+		},
+		"create request": {
+			schema:       "NewUser",
+			wantProps:    []string{"email", "name", "password"},
+			wantRequired: []string{"name", "email", "password"},
+		},
+		"update request": {
+			schema:    "UpdateUser",
+			wantProps: []string{"enabled", "name", "password"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			schema := doc.Components.Schemas[tc.schema]
+			props := []string{}
+			for prop := range schema.Properties {
+				props = append(props, prop)
+			}
+			sort.Strings(props)
+			if !reflect.DeepEqual(props, tc.wantProps) {
+				t.Errorf("properties = %v, want %v", props, tc.wantProps)
+			}
+			if strings.Join(schema.Required, ",") != strings.Join(tc.wantRequired, ",") {
+				t.Errorf("required = %v, want %v", schema.Required, tc.wantRequired)
+			}
+		})
+	}
+}
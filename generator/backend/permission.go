@@ -0,0 +1,454 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// permissionCheck is the permission check of an action called by the core.
+type permissionCheck struct {
+	action string
+	name   string
+	// param is the parameter of the input of the action, the check only has the context
+	// when it is empty.
+	param string
+	// input is the input passed to the authorizer.
+	input string
+}
+
+// permissionChecks returns the permission checks of the actions of the entity.
+func permissionChecks(entity generator.Entity) []permissionCheck {
+	newName, updateName := "New"+entity.Struct, "Update"+entity.Struct
+	newVar, updateVar := receiverName(newName), receiverName(updateName)
+	return []permissionCheck{
+		{generator.ActionCreate, "checkCreatePermission", newVar + " " + newName, newVar},
+		{generator.ActionGet, "checkGetPermission", "id string", "id"},
+		{generator.ActionUpdate, "checkUpdatePermission", updateVar + " " + updateName, updateVar},
+		{generator.ActionDelete, "checkDeletePermission", "id string", "id"},
+		{generator.ActionQuery, "checkQueryPermission", "", "nil"},
+	}
+}
+
+// PermissionFiles returns permission.go with the permission checks the core calls, e.g.
+// checkCreatePermission, and permission_test.go with their tests. The checks follow the
+// policy model of the entity: the role model checks the roles of the claims of the context,
+// the owner model compares the owner field to the subject of the claims and the authorizer
+// model calls the auth.Authorizer set with SetAuthorizer. An entity without a policy allows
+// every action to the authenticated users.
+func PermissionFiles(entity generator.Entity, modulePath string) ([]generator.File, error) {
+	if err := entity.Validate(); err != nil {
+		return nil, fmt.Errorf("Validate: %w", err)
+	}
+
+	policy := generator.Policy{Model: generator.RolePolicy}
+	if entity.Policy != nil {
+		policy = *entity.Policy
+	}
+	if _, ok := entity.Field(policy.Owner); policy.Model == generator.OwnerPolicy && !ok {
+		return nil, fmt.Errorf("owner %q is not a field of %s", policy.Owner, entity.Struct)
+	}
+
+	code, err := permissionCode(entity, policy, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("permissionCode: %w", err)
+	}
+	test, err := permissionTest(entity, policy, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("permissionTest: %w", err)
+	}
+
+	dir := path.Join(BusinessDir, entity.Name)
+	return []generator.File{
+		{Path: path.Join(dir, "permission.go"), Content: code},
+		{Path: path.Join(dir, "permission_test.go"), Content: test},
+	}, nil
+}
+
+func permissionCode(entity generator.Entity, policy generator.Policy, modulePath string) (string, error) {
+	imports := []string{"context", "fmt", modulePath + "/pkg/apperrors", modulePath + "/pkg/auth"}
+	owner, _ := entity.Field(policy.Owner)
+	if policy.Model == generator.OwnerPolicy && owner.Type == "uuid.UUID" {
+		imports = append(imports, uuidPkg)
+	}
+
+	body := bytes.Buffer{}
+	switch policy.Model {
+	case generator.RolePolicy:
+		body.WriteString(rolePermissions(entity, policy))
+	case generator.OwnerPolicy:
+		body.WriteString(ownerPermissions(entity, policy, owner))
+	case generator.AuthorizerPolicy:
+		body.WriteString(authorizerPermissions(entity))
+	default:
+		return "", fmt.Errorf("unknown policy model %q", policy.Model)
+	}
+
+	return goFile(entity.Name, imports, body.String())
+}
+
+func rolePermissions(entity generator.Entity, policy generator.Policy) string {
+	buf := bytes.Buffer{}
+	buf.WriteString("// list of roles allowed per action, every authenticated user is allowed the actions without roles\nvar (\n")
+	for _, action := range generator.Actions {
+		buf.WriteString(fmt.Sprintf("\t%sRoles %s\n", action, goStrings(policy.Roles[action])))
+	}
+	buf.WriteString(")\n")
+
+	for _, check := range permissionChecks(entity) {
+		buf.WriteString("\n" + checkFunc(entity, check, fmt.Sprintf("return checkRoles(ctx, %sRoles)", check.action)))
+	}
+
+	buf.WriteString(`
+// checkRoles checks the claims of the context have one of the roles.
+func checkRoles(ctx context.Context, roles []string) error {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return apperrors.ErrUnauthenticated
+	}
+	if len(roles) > 0 && !claims.HasRole(roles...) {
+		return fmt.Errorf("%w: %s needs one of the roles %v", apperrors.ErrForbidden, claims.Subject, roles)
+	}
+	return nil
+}
+`)
+	return buf.String()
+}
+
+func ownerPermissions(entity generator.Entity, policy generator.Policy, owner generator.Field) string {
+	v := receiverName(entity.Struct)
+	plural := generator.Plural(generator.SnakeCase(entity.Struct))
+
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("// adminRoles are allowed on every %s.\nvar adminRoles %s\n", generator.SnakeCase(entity.Struct), goStrings(policy.Admins)))
+
+	for _, check := range permissionChecks(entity) {
+		body := "return checkAuthenticated(ctx)"
+		if check.action == generator.ActionCreate {
+			body = fmt.Sprintf("return checkOwner(ctx, %s.%s)", receiverName("New"+entity.Struct), owner.Name)
+		}
+		buf.WriteString("\n" + checkFunc(entity, check, body))
+	}
+
+	ownerString, parseOwner := "owner", "owner := claims.Subject"
+	if owner.Type == "uuid.UUID" {
+		ownerString = "owner.String()"
+		parseOwner = `owner, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Filter{}, fmt.Errorf("%w: subject %q is not a uuid", apperrors.ErrForbidden, claims.Subject)
+	}`
+	}
+
+	buf.WriteString(fmt.Sprintf(`
+// checkOwnerPermission checks the subject of the claims of the context owns the %[2]s, the
+// core calls it with the loaded %[2]s before returning, updating or deleting it.
+func checkOwnerPermission(ctx context.Context, %[1]s %[3]s) error {
+	return checkOwner(ctx, %[1]s.%[4]s)
+}
+
+// ownerFilter restricts the filter to the %[5]s of the subject of the claims of the context,
+// unless the claims have an admin role. The core applies it to the filter of Query.
+func ownerFilter(ctx context.Context, f Filter) (Filter, error) {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return Filter{}, apperrors.ErrUnauthenticated
+	}
+	if claims.HasRole(adminRoles...) {
+		return f, nil
+	}
+
+	%[6]s
+	f.%[4]s = &owner
+	return f, nil
+}
+
+// checkOwner checks the subject of the claims of the context is the owner, or the claims
+// have an admin role.
+func checkOwner(ctx context.Context, owner %[7]s) error {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return apperrors.ErrUnauthenticated
+	}
+	if %[8]s != claims.Subject && !claims.HasRole(adminRoles...) {
+		return fmt.Errorf("%%w: %%s is not the owner", apperrors.ErrForbidden, claims.Subject)
+	}
+	return nil
+}
+
+// checkAuthenticated checks the context has the claims of an authenticated user.
+func checkAuthenticated(ctx context.Context) error {
+	if _, ok := auth.ClaimsFrom(ctx); !ok {
+		return apperrors.ErrUnauthenticated
+	}
+	return nil
+}
+`, v, generator.SnakeCase(entity.Struct), entity.Struct, owner.Name, plural, parseOwner, owner.Type, ownerString))
+	return buf.String()
+}
+
+func authorizerPermissions(entity generator.Entity) string {
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf(`// authorizer decides the permissions of the %[1]s actions, the application sets it with
+// SetAuthorizer. Every action is forbidden until it is set.
+var authorizer auth.Authorizer
+
+// SetAuthorizer sets the authorizer of the permissions of the %[1]s actions, e.g. an adapter
+// of OPA or casbin.
+func SetAuthorizer(a auth.Authorizer) {
+	authorizer = a
+}
+`, entity.Name))
+
+	for _, check := range permissionChecks(entity) {
+		buf.WriteString("\n" + checkFunc(entity, check, fmt.Sprintf("return authorize(ctx, %q, %s)", check.action, check.input)))
+	}
+
+	buf.WriteString(fmt.Sprintf(`
+// authorize asks the authorizer whether the claims of the context are allowed the action.
+func authorize(ctx context.Context, action string, input any) error {
+	claims, ok := auth.ClaimsFrom(ctx)
+	if !ok {
+		return apperrors.ErrUnauthenticated
+	}
+	if authorizer == nil {
+		return fmt.Errorf("%%w: no authorizer", apperrors.ErrForbidden)
+	}
+
+	allowed, err := authorizer.Authorize(ctx, auth.Request{Claims: claims, Action: action, Resource: %q, Input: input})
+	if err != nil {
+		return fmt.Errorf("authorizer.Authorize: %%w", err)
+	}
+	if !allowed {
+		return fmt.Errorf("%%w: %%s is not allowed to %%s the %s", apperrors.ErrForbidden, claims.Subject, action)
+	}
+	return nil
+}
+`, entity.Name, generator.SnakeCase(entity.Struct)))
+	return buf.String()
+}
+
+// checkFunc returns the function of the permission check with the body.
+func checkFunc(entity generator.Entity, check permissionCheck, body string) string {
+	params := "ctx context.Context"
+	if check.param != "" {
+		params += ", " + check.param
+	}
+
+	object := "the " + generator.SnakeCase(entity.Struct)
+	if check.action == generator.ActionQuery {
+		object = "the " + generator.Plural(generator.SnakeCase(entity.Struct))
+	}
+	return fmt.Sprintf("// %s checks the claims of the context are allowed to %s %s.\nfunc %s(%s) error {\n\t%s\n}\n",
+		check.name, check.action, object, check.name, params, body)
+}
+
+func permissionTest(entity generator.Entity, policy generator.Policy, modulePath string) (string, error) {
+	imports := []string{"context", "errors", "testing", modulePath + "/pkg/apperrors", modulePath + "/pkg/auth"}
+	owner, _ := entity.Field(policy.Owner)
+
+	calls := map[string]string{}
+	for _, check := range permissionChecks(entity) {
+		switch {
+		case check.param == "":
+			calls[check.action] = check.name + "(ctx)"
+		case strings.HasSuffix(check.param, " string"):
+			calls[check.action] = check.name + `(ctx, "id")`
+		default:
+			calls[check.action] = check.name + "(ctx, " + strings.Fields(check.param)[1] + "{})"
+		}
+	}
+
+	type testCase struct {
+		name   string
+		claims string
+		call   string
+		want   string
+	}
+	cases := []testCase{}
+	setup := ""
+
+	switch policy.Model {
+	case generator.RolePolicy:
+		for _, action := range generator.Actions {
+			cases = append(cases, testCase{action + " without claims", "", calls[action], "apperrors.ErrUnauthenticated"})
+			roles := policy.Roles[action]
+			if len(roles) == 0 {
+				cases = append(cases, testCase{action + " authenticated", `auth.Claims{Subject: "subject"}`, calls[action], "nil"})
+				continue
+			}
+			cases = append(cases,
+				testCase{action + " with role " + roles[0], fmt.Sprintf(`auth.Claims{Subject: "subject", Roles: []string{%q}}`, roles[0]), calls[action], "nil"},
+				testCase{action + " without role", `auth.Claims{Subject: "subject", Roles: []string{"codegen-test-role"}}`, calls[action], "apperrors.ErrForbidden"},
+			)
+		}
+
+	case generator.OwnerPolicy:
+		ownerValue, otherValue := `"owner"`, `"other"`
+		if owner.Type == "uuid.UUID" {
+			imports = append(imports, uuidPkg)
+			ownerValue, otherValue = "ownerID", "uuid.New()"
+			setup = "\townerID := uuid.New()\n\tsubject := ownerID.String()\n"
+		} else {
+			setup = "\tsubject := " + ownerValue + "\n"
+		}
+
+		ownerClaims := "auth.Claims{Subject: subject}"
+		createCall := func(value string) string {
+			return fmt.Sprintf("checkCreatePermission(ctx, New%s{%s: %s})", entity.Struct, owner.Name, value)
+		}
+		ownerCall := func(value string) string {
+			return fmt.Sprintf("checkOwnerPermission(ctx, %s{%s: %s})", entity.Struct, owner.Name, value)
+		}
+		cases = append(cases,
+			testCase{"create without claims", "", createCall(ownerValue), "apperrors.ErrUnauthenticated"},
+			testCase{"create own", ownerClaims, createCall(ownerValue), "nil"},
+			testCase{"create for another owner", ownerClaims, createCall(otherValue), "apperrors.ErrForbidden"},
+			testCase{"own", ownerClaims, ownerCall(ownerValue), "nil"},
+			testCase{"another owner", ownerClaims, ownerCall(otherValue), "apperrors.ErrForbidden"},
+		)
+		if len(policy.Admins) > 0 {
+			adminClaims := fmt.Sprintf(`auth.Claims{Subject: subject, Roles: []string{%q}}`, policy.Admins[0])
+			cases = append(cases,
+				testCase{"create for another owner as " + policy.Admins[0], adminClaims, createCall(otherValue), "nil"},
+				testCase{"another owner as " + policy.Admins[0], adminClaims, ownerCall(otherValue), "nil"},
+			)
+		}
+		for _, action := range generator.Actions[1:] {
+			cases = append(cases,
+				testCase{action + " without claims", "", calls[action], "apperrors.ErrUnauthenticated"},
+				testCase{action + " authenticated", ownerClaims, calls[action], "nil"},
+			)
+		}
+
+	case generator.AuthorizerPolicy:
+		setup = fmt.Sprintf(`	// the authorizer only allows the %s action
+	SetAuthorizer(auth.AuthorizerFunc(func(ctx context.Context, req auth.Request) (bool, error) {
+		return req.Resource == %q && req.Action == %q, nil
+	}))
+	t.Cleanup(func() { SetAuthorizer(nil) })
+`, generator.ActionCreate, entity.Name, generator.ActionCreate)
+		cases = append(cases,
+			testCase{"create without claims", "", calls[generator.ActionCreate], "apperrors.ErrUnauthenticated"},
+			testCase{"create allowed", `auth.Claims{Subject: "subject"}`, calls[generator.ActionCreate], "nil"},
+		)
+		for _, action := range generator.Actions[1:] {
+			cases = append(cases, testCase{action + " not allowed", `auth.Claims{Subject: "subject"}`, calls[action], "apperrors.ErrForbidden"})
+		}
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("func TestPermissions(t *testing.T) {\n%s\n", setup))
+	buf.WriteString("\ttests := []struct {\n\t\tname   string\n\t\tclaims *auth.Claims\n\t\tcheck  func(ctx context.Context) error\n\t\twant   error\n\t}{\n")
+	for _, c := range cases {
+		claims := "nil"
+		if c.claims != "" {
+			claims = "&" + c.claims
+		}
+		buf.WriteString(fmt.Sprintf("\t\t{\n\t\t\tname:   %q,\n\t\t\tclaims: %s,\n\t\t\tcheck:  func(ctx context.Context) error { return %s },\n\t\t\twant:   %s,\n\t\t},\n",
+			c.name, claims, c.call, c.want))
+	}
+	buf.WriteString(`	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = auth.WithClaims(ctx, *tt.claims)
+			}
+
+			if err := tt.check(ctx); !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+`)
+
+	if policy.Model == generator.OwnerPolicy {
+		buf.WriteString(ownerFilterTest(policy, owner))
+	}
+
+	return goFile(entity.Name, imports, buf.String())
+}
+
+// ownerFilterTest returns the test of the owner filter.
+func ownerFilterTest(policy generator.Policy, owner generator.Field) string {
+	setup, want := "\tsubject := \"owner\"\n", "subject"
+	if owner.Type == "uuid.UUID" {
+		setup, want = "\townerID := uuid.New()\n\tsubject := ownerID.String()\n", "ownerID"
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf(`
+func TestOwnerFilter(t *testing.T) {
+%s
+	ctx := auth.WithClaims(context.Background(), auth.Claims{Subject: subject})
+	f, err := ownerFilter(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("ownerFilter: %%v", err)
+	}
+	if f.%[2]s == nil || *f.%[2]s != %[3]s {
+		t.Fatalf("got owner %%v, want %%v", f.%[2]s, %[3]s)
+	}
+
+	if _, err := ownerFilter(context.Background(), Filter{}); !errors.Is(err, apperrors.ErrUnauthenticated) {
+		t.Fatalf("got error %%v, want %%v", err, apperrors.ErrUnauthenticated)
+	}
+`, setup, owner.Name, want))
+
+	if len(policy.Admins) > 0 {
+		buf.WriteString(fmt.Sprintf(`
+	ctx = auth.WithClaims(context.Background(), auth.Claims{Subject: subject, Roles: []string{%q}})
+	if f, err := ownerFilter(ctx, Filter{}); err != nil || f.%s != nil {
+		t.Fatalf("got filter %%v, error %%v, want the filter of every owner", f, err)
+	}
+`, policy.Admins[0], owner.Name))
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// goStrings returns the go literal of the strings, or the type of a nil slice when empty.
+func goStrings(values []string) string {
+	if len(values) == 0 {
+		return "[]string"
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return "= []string{" + strings.Join(quoted, ", ") + "}"
+}
+
+// goFile returns the formatted go file of the package with the imports and the body.
+func goFile(pkg string, imports []string, body string) (string, error) {
+	sort.Slice(imports, func(i, j int) bool {
+		if isStdPkg(imports[i]) != isStdPkg(imports[j]) {
+			return isStdPkg(imports[i])
+		}
+		return imports[i] < imports[j]
+	})
+
+	buf := bytes.Buffer{}
+	buf.WriteString("// Code generated by codegenerator. DO NOT EDIT.\n\n")
+	buf.WriteString("package " + pkg + "\n\nimport (\n")
+	for i, path := range imports {
+		if i > 0 && isStdPkg(imports[i-1]) != isStdPkg(path) {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\t\"" + path + "\"\n")
+	}
+	buf.WriteString(")\n\n")
+	buf.WriteString(body)
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("format.Source: %w", err)
+	}
+	return string(code), nil
+}
@@ -0,0 +1,464 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// protoType maps a go type to a protobuf type.
+type protoType struct {
+	name     string
+	message  bool
+	repeated bool
+	// toProto formats the expression which converts the go value to protobuf.
+	toProto string
+	// fromProto formats the expression which converts the protobuf value to go.
+	fromProto string
+	// parse is the function which parses the protobuf value, it is used instead of fromProto.
+	parse string
+	// deref is set when parse returns a pointer.
+	deref bool
+	// elem is the type of the values of a repeated field converted one by one, e.g. the
+	// uuid.UUID of a []uuid.UUID.
+	elem   *protoType
+	goPkgs []string
+}
+
+// list of imports of the conversions
+const (
+	uuidPkg        = "github.com/google/uuid"
+	mailPkg        = "net/mail"
+	timestamppbPkg = "google.golang.org/protobuf/types/known/timestamppb"
+	timestampProto = "google/protobuf/timestamp.proto"
+	emptyProto     = "google/protobuf/empty.proto"
+)
+
+// protoTypes maps go types to protobuf types.
+var protoTypes = map[string]protoType{
+	"string":       {name: "string", toProto: "%s", fromProto: "%s"},
+	"bool":         {name: "bool", toProto: "%s", fromProto: "%s"},
+	"int":          {name: "int64", toProto: "int64(%s)", fromProto: "int(%s)"},
+	"int64":        {name: "int64", toProto: "%s", fromProto: "%s"},
+	"int32":        {name: "int32", toProto: "%s", fromProto: "%s"},
+	"uint":         {name: "uint64", toProto: "uint64(%s)", fromProto: "uint(%s)"},
+	"uint64":       {name: "uint64", toProto: "%s", fromProto: "%s"},
+	"uint32":       {name: "uint32", toProto: "%s", fromProto: "%s"},
+	"float64":      {name: "double", toProto: "%s", fromProto: "%s"},
+	"float32":      {name: "float", toProto: "%s", fromProto: "%s"},
+	"[]byte":       {name: "bytes", toProto: "%s", fromProto: "%s"},
+	"[]string":     {name: "string", repeated: true, toProto: "%s", fromProto: "%s"},
+	"uuid.UUID":    {name: "string", toProto: "%s.String()", parse: "uuid.Parse", goPkgs: []string{uuidPkg}},
+	"mail.Address": {name: "string", toProto: "%s.Address", parse: "mail.ParseAddress", deref: true, goPkgs: []string{mailPkg}},
+	"time.Time": {
+		name:      "google.protobuf.Timestamp",
+		message:   true,
+		toProto:   "timestamppb.New(%s)",
+		fromProto: "%s.AsTime()",
+		goPkgs:    []string{timestamppbPkg},
+	},
+}
+
+// protoTypeOf returns the protobuf type of the field. Types declared in the entity package
+// are converted to string, slices of the mapped scalar types are repeated, types of other
+// packages have no mapping.
+func protoTypeOf(pkg string, field Field) (protoType, bool) {
+	if t, ok := protoTypes[field.Type]; ok {
+		return t, true
+	}
+	if elemType, ok := strings.CutPrefix(field.Type, "[]"); ok {
+		elem, ok := protoTypes[elemType]
+		if !ok || elem.message || elem.repeated {
+			return protoType{}, false
+		}
+		return protoType{name: elem.name, repeated: true, elem: &elem, goPkgs: elem.goPkgs}, true
+	}
+	if strings.ContainsAny(field.Type, ".[]*") {
+		return protoType{}, false
+	}
+	return protoType{name: "string", toProto: "string(%s)", fromProto: pkg + "." + field.Type + "(%s)"}, true
+}
+
+// ProtoFiles returns the .proto file with the messages and the CRUD service of the entity
+// and the go code which converts between the protobuf and the domain types. The messages
+// are the structs of the package of the entity, see EntityPackage, without the sensitive
+// fields of the entity.
+func ProtoFiles(entityPkg Package, entity generator.Entity, modulePath string) ([]generator.File, error) {
+	entityPkg = entityPkg.withoutSensitive(entity)
+	model, filter := entityPkg.Model, entityPkg.Filter
+	pkg := model.Package
+	goPackage := modulePath + "/gen/proto/" + pkg + "/v1"
+
+	protoFile := generator.File{
+		Path:    fmt.Sprintf("proto/%s/v1/%s.proto", pkg, pkg),
+		Content: protoDefinition(model, filter, goPackage),
+	}
+
+	convert, err := protoConversions(model, filter, modulePath+"/business/"+pkg, goPackage)
+	if err != nil {
+		return nil, fmt.Errorf("protoConversions: %w", err)
+	}
+
+	return []generator.File{
+		protoFile,
+		{Path: fmt.Sprintf("handler/%sgrpc/convert.go", pkg), Content: convert},
+	}, nil
+}
+
+func protoDefinition(model Model, filter Struct, goPackage string) string {
+	entity := model.Entity.Name
+	plural := upperFirst(generator.Plural(model.Entity.Name))
+	messages := []Struct{model.Entity, model.New, model.Update, filter}
+
+	buf := bytes.Buffer{}
+	buf.WriteString("syntax = \"proto3\";\n\n")
+	buf.WriteString("package " + model.Package + ".v1;\n\n")
+	buf.WriteString("import \"" + emptyProto + "\";\n")
+	if usesProtoTimestamp(model.Package, messages) {
+		buf.WriteString("import \"" + timestampProto + "\";\n")
+	}
+	buf.WriteString("\n")
+	buf.WriteString(fmt.Sprintf("option go_package = \"%s;%sv1\";\n\n", goPackage, model.Package))
+
+	buf.WriteString("service " + entity + "Service {\n")
+	buf.WriteString(fmt.Sprintf("  rpc Create(Create%[1]sRequest) returns (Create%[1]sResponse);\n", entity))
+	buf.WriteString(fmt.Sprintf("  rpc Update(Update%[1]sRequest) returns (Update%[1]sResponse);\n", entity))
+	buf.WriteString(fmt.Sprintf("  rpc Delete(Delete%[1]sRequest) returns (google.protobuf.Empty);\n", entity))
+	buf.WriteString(fmt.Sprintf("  rpc Get(Get%[1]sRequest) returns (Get%[1]sResponse);\n", entity))
+	buf.WriteString(fmt.Sprintf("  rpc List(List%[1]sRequest) returns (List%[1]sResponse);\n", plural))
+	buf.WriteString("}\n")
+
+	for _, message := range messages {
+		if message.Name == "" {
+			continue
+		}
+		buf.WriteString("\n" + protoMessage(model.Package, message))
+	}
+
+	buf.WriteString(fmt.Sprintf(`
+message Create%[1]sRequest {
+  New%[1]s %[2]s = 1;
+}
+
+message Create%[1]sResponse {
+  %[1]s %[2]s = 1;
+}
+
+message Update%[1]sRequest {
+  Update%[1]s %[2]s = 1;
+}
+
+message Update%[1]sResponse {
+  %[1]s %[2]s = 1;
+}
+
+message Delete%[1]sRequest {
+  string id = 1;
+}
+
+message Get%[1]sRequest {
+  string id = 1;
+}
+
+message Get%[1]sResponse {
+  %[1]s %[2]s = 1;
+}
+
+message List%[3]sRequest {
+  Filter filter = 1;
+  string order_by = 2;
+  string direction = 3;
+  int32 page = 4;
+  int32 rows_per_page = 5;
+}
+
+message List%[3]sResponse {
+  repeated %[1]s %[4]s = 1;
+  int32 page = 2;
+  int32 rows_per_page = 3;
+}
+`, entity, generator.SnakeCase(entity), plural, generator.SnakeCase(plural)))
+
+	return buf.String()
+}
+
+// protoMessage returns the message of the struct.
+func protoMessage(pkg string, s Struct) string {
+	buf := bytes.Buffer{}
+	buf.WriteString("message " + s.Name + " {\n")
+
+	number := 1
+	for _, field := range s.Fields {
+		t, ok := protoTypeOf(pkg, field)
+		if !ok {
+			buf.WriteString(fmt.Sprintf("  // %s has no protobuf mapping for %s\n", field.Name, field.Type))
+			continue
+		}
+
+		label := ""
+		switch {
+		case t.repeated:
+			label = "repeated "
+		case isProtoOptional(s, field, t):
+			label = "optional "
+		}
+
+		buf.WriteString(fmt.Sprintf("  %s%s %s = %d;\n", label, t.name, generator.SnakeCase(field.Name), number))
+		number++
+	}
+
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+func usesProtoTimestamp(pkg string, structs []Struct) bool {
+	for _, s := range structs {
+		for _, field := range s.Fields {
+			if t, ok := protoTypeOf(pkg, field); ok && t.message {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func protoConversions(model Model, filter Struct, entityImport, goPackage string) (string, error) {
+	pkg := model.Package
+	pbPkg := pkg + "v1"
+	imports := map[string]bool{entityImport: true, goPackage: true}
+
+	body := bytes.Buffer{}
+	body.WriteString(toProtoFunc(pkg, pbPkg, model.Entity, imports))
+	for _, s := range []Struct{model.New, model.Update, filter} {
+		if s.Name == "" {
+			continue
+		}
+		body.WriteString("\n" + fromProtoFunc(pkg, pbPkg, s, imports))
+	}
+
+	paths := []string{}
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if isStdPkg(paths[i]) != isStdPkg(paths[j]) {
+			return isStdPkg(paths[i])
+		}
+		return paths[i] < paths[j]
+	})
+
+	buf := bytes.Buffer{}
+	buf.WriteString("// Code generated by codegenerator. DO NOT EDIT.\n\n")
+	buf.WriteString("package " + pkg + "grpc\n\nimport (\n")
+	for i, path := range paths {
+		if i > 0 && isStdPkg(paths[i-1]) != isStdPkg(path) {
+			buf.WriteString("\n")
+		}
+		if path == goPackage {
+			buf.WriteString(pbPkg + " ")
+		}
+		buf.WriteString("\"" + path + "\"\n")
+	}
+	buf.WriteString(")\n\n")
+	buf.Write(body.Bytes())
+
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("format.Source: %w", err)
+	}
+	return string(code), nil
+}
+
+func toProtoFunc(pkg, pbPkg string, s Struct, imports map[string]bool) string {
+	v := receiverName(s.Name)
+
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("// toProto%[1]s converts the %[1]s to protobuf.\n", s.Name))
+	buf.WriteString(fmt.Sprintf("func toProto%[1]s(%[2]s %[3]s.%[1]s) *%[4]s.%[1]s {\n", s.Name, v, pkg, pbPkg))
+	buf.WriteString(fmt.Sprintf("pb := %s.%s{}\n", pbPkg, s.Name))
+
+	for _, field := range s.Fields {
+		t, ok := protoTypeOf(pkg, field)
+		if !ok {
+			continue
+		}
+		addImports(imports, t.goPkgs)
+
+		goValue := v + "." + field.Name
+		pbField := "pb." + protoGoName(field.Name)
+		switch {
+		case t.elem != nil:
+			buf.WriteString("for _, v := range " + goValue + " {\n")
+			buf.WriteString(pbField + " = append(" + pbField + ", " + fmt.Sprintf(t.elem.toProto, "v") + ")\n}\n")
+		case !field.Pointer || t.toProto == "%s":
+			buf.WriteString(pbField + " = " + fmt.Sprintf(t.toProto, goValue) + "\n")
+		case t.message || t.repeated:
+			buf.WriteString("if " + goValue + " != nil {\n")
+			buf.WriteString(pbField + " = " + fmt.Sprintf(t.toProto, "*"+goValue) + "\n}\n")
+		default:
+			buf.WriteString("if " + goValue + " != nil {\n")
+			buf.WriteString("v := " + fmt.Sprintf(t.toProto, "*"+goValue) + "\n")
+			buf.WriteString(pbField + " = &v\n}\n")
+		}
+	}
+
+	buf.WriteString("return &pb\n}\n")
+	return buf.String()
+}
+
+func fromProtoFunc(pkg, pbPkg string, s Struct, imports map[string]bool) string {
+	v := receiverName(s.Name)
+	zero := pkg + "." + s.Name + "{}"
+
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("// toCore%[1]s converts the protobuf %[1]s to the domain type.\n", s.Name))
+	buf.WriteString(fmt.Sprintf("func toCore%[1]s(pb *%[2]s.%[1]s) (%[3]s.%[1]s, error) {\n", s.Name, pbPkg, pkg))
+	buf.WriteString(v + " := " + zero + "\n")
+
+	for _, field := range s.Fields {
+		t, ok := protoTypeOf(pkg, field)
+		if !ok {
+			continue
+		}
+		addImports(imports, t.goPkgs)
+
+		pbValue := "pb." + protoGoName(field.Name)
+		goField := v + "." + field.Name
+		if t.elem != nil {
+			buf.WriteString(fromProtoElems(goField, pbValue, zero, *t.elem, imports))
+			continue
+		}
+		optional := isProtoOptional(s, field, t)
+		if optional && field.Pointer && t.fromProto == "%s" {
+			buf.WriteString(goField + " = " + pbValue + "\n")
+			continue
+		}
+		if optional {
+			buf.WriteString("if " + pbValue + " != nil {\n")
+			pbValue = "*" + pbValue
+		} else if field.Pointer {
+			buf.WriteString("if " + pbValue + " != nil {\n")
+		}
+
+		if t.parse != "" {
+			imports["fmt"] = true
+			name := varName(field.Name)
+			buf.WriteString(fmt.Sprintf("%s, err := %s(%s)\n", name, t.parse, pbValue))
+			buf.WriteString("if err != nil {\n")
+			buf.WriteString(fmt.Sprintf("return %s, fmt.Errorf(\"%s[%%v]: %%w\", %s, err)\n}\n", zero, t.parse, pbValue))
+			switch {
+			case field.Pointer == t.deref:
+				buf.WriteString(goField + " = " + name + "\n")
+			case t.deref:
+				buf.WriteString(goField + " = *" + name + "\n")
+			default:
+				buf.WriteString(goField + " = &" + name + "\n")
+			}
+		} else if field.Pointer {
+			buf.WriteString("v := " + fmt.Sprintf(t.fromProto, pbValue) + "\n")
+			buf.WriteString(goField + " = &v\n")
+		} else {
+			buf.WriteString(goField + " = " + fmt.Sprintf(t.fromProto, pbValue) + "\n")
+		}
+
+		if optional || field.Pointer {
+			buf.WriteString("}\n")
+		}
+	}
+
+	buf.WriteString("return " + v + ", nil\n}\n")
+	return buf.String()
+}
+
+// fromProtoElems converts the values of a repeated field one by one.
+func fromProtoElems(goField, pbValue, zero string, elem protoType, imports map[string]bool) string {
+	buf := bytes.Buffer{}
+	buf.WriteString("for _, v := range " + pbValue + " {\n")
+	if elem.parse == "" {
+		buf.WriteString(goField + " = append(" + goField + ", " + fmt.Sprintf(elem.fromProto, "v") + ")\n}\n")
+		return buf.String()
+	}
+
+	imports["fmt"] = true
+	buf.WriteString(fmt.Sprintf("value, err := %s(v)\n", elem.parse))
+	buf.WriteString("if err != nil {\n")
+	buf.WriteString(fmt.Sprintf("return %s, fmt.Errorf(\"%s[%%v]: %%w\", v, err)\n}\n", zero, elem.parse))
+	if elem.deref {
+		buf.WriteString(goField + " = append(" + goField + ", *value)\n}\n")
+	} else {
+		buf.WriteString(goField + " = append(" + goField + ", value)\n}\n")
+	}
+	return buf.String()
+}
+
+// isProtoOptional reports whether the scalar field is optional in protobuf. Pointer fields
+// are optional, and all fields but the id of the update and filter messages.
+func isProtoOptional(s Struct, field Field, t protoType) bool {
+	if t.message || t.repeated {
+		return false
+	}
+	if field.Pointer {
+		return true
+	}
+	return (strings.HasPrefix(s.Name, "Update") || s.Name == "Filter") && field.Name != "ID"
+}
+
+func addImports(imports map[string]bool, paths []string) {
+	for _, path := range paths {
+		imports[path] = true
+	}
+}
+
+// protoGoName returns the name of the go field protoc generates for the field.
+func protoGoName(name string) string {
+	parts := strings.Split(generator.SnakeCase(name), "_")
+	for i := range parts {
+		parts[i] = upperFirst(parts[i])
+	}
+	return strings.Join(parts, "")
+}
+
+// receiverName returns the initials of the type name, e.g. nu for NewUser.
+func receiverName(name string) string {
+	initials := ""
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			initials += string(unicode.ToLower(r))
+		}
+	}
+	return initials
+}
+
+// isStdPkg reports whether the import path belongs to the standard library.
+func isStdPkg(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+// varName returns the lower camel case of the field name, e.g. managerID for ManagerID.
+func varName(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) || (i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	name = string(runes)
+	if token.IsKeyword(name) {
+		return name + "Value"
+	}
+	return name
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
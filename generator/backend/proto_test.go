@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+func TestProtoFiles(t *testing.T) {
+	orderItem := generator.Entity{
+		Name:      "orderitem",
+		Table:     "order_items",
+		Versioned: true,
+		Fields: []generator.Field{
+			{Name: "ID", Type: "uuid.UUID", Sortable: true},
+			{Name: "OrderID", Type: "uuid.UUID"},
+			{Name: "Quantity", Type: "int", Updatable: true, Operators: []generator.Operator{generator.RangeOperator}},
+			{Name: "Note", Type: "string", Nullable: true, Updatable: true},
+			{Name: "CreatedAt", Type: "time.Time", Sortable: true},
+		},
+		Relations: []generator.Relation{{Name: "Order", Kind: generator.BelongsTo, Entity: "order", Field: "OrderID"}},
+	}
+	orderItem.SetDefaults()
+
+	tag := generator.Entity{Name: "tag", Fields: []generator.Field{{Name: "ID", Type: "int64"}, {Name: "Label", Type: "string", Updatable: true}}}
+	tag.SetDefaults()
+
+	user := generator.Entity{
+		Name: "user",
+		Fields: []generator.Field{
+			{Name: "ID", Type: "uuid.UUID"},
+			{Name: "Name", Type: "string", Updatable: true},
+			{Name: "PasswordHash", Type: "[]byte", Updatable: true},
+			{Name: "APIKey", Type: "string", Sensitive: true},
+		},
+	}
+	user.SetDefaults()
+
+	testCases := map[string]struct {
+		entity      generator.Entity
+		wantPaths   []string
+		wantProto   []string
+		wantConvert []string
+		wantNot     []string
+	}{
+		"multi-word entity": {
+			entity:    orderItem,
+			wantPaths: []string{"proto/orderitem/v1/orderitem.proto", "handler/orderitemgrpc/convert.go"},
+			wantProto: []string{
+				"package orderitem.v1;",
+				"import \"google/protobuf/empty.proto\";\nimport \"google/protobuf/timestamp.proto\";",
+				"option go_package = \"example.com/shop/gen/proto/orderitem/v1;orderitemv1\";",
+				"service OrderItemService {",
+				"rpc Delete(DeleteOrderItemRequest) returns (google.protobuf.Empty);",
+				"rpc List(ListOrderItemsRequest) returns (ListOrderItemsResponse);",
+				"message DeleteOrderItemRequest {\n  string id = 1;\n}",
+				"optional string note = 4;",
+				"message NewOrderItem {\n  string order_id = 1;\n  int64 quantity = 2;\n  optional string note = 3;\n}",
+				"message UpdateOrderItem {\n  string id = 1;",
+				"optional int64 start_quantity = 2;",
+			},
+			wantConvert: []string{
+				"func toProtoOrderItem(oi orderitem.OrderItem) *orderitemv1.OrderItem {",
+				"pb.CreatedAt = timestamppb.New(oi.CreatedAt)",
+				"func toCoreUpdateOrderItem(pb *orderitemv1.UpdateOrderItem) (orderitem.UpdateOrderItem, error) {",
+				"func toCoreFilter(pb *orderitemv1.Filter) (orderitem.Filter, error) {",
+			},
+		},
+		"no filter": {
+			entity:      tag,
+			wantPaths:   []string{"proto/tag/v1/tag.proto", "handler/taggrpc/convert.go"},
+			wantProto:   []string{"service TagService {", "message NewTag {\n  string label = 1;\n}"},
+			wantConvert: []string{"pb.Id = t.ID"},
+			wantNot:     []string{"timestamp", "toCoreFilter"},
+		},
+		"sensitive fields": {
+			entity:    user,
+			wantPaths: []string{"proto/user/v1/user.proto", "handler/usergrpc/convert.go"},
+			wantProto: []string{
+				"message User {\n  string id = 1;\n  string name = 2;\n}",
+				"message NewUser {\n  string name = 1;\n}",
+				"message UpdateUser {\n  string id = 1;\n  optional string name = 2;\n}",
+			},
+			wantConvert: []string{"pb.Name = u.Name"},
+			wantNot:     []string{"password", "PasswordHash", "api_key", "APIKey"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			files, err := ProtoFiles(NewPackage(tc.entity), tc.entity, "example.com/shop")
+			if err != nil {
+				t.Fatalf("ProtoFiles: %v", err)
+			}
+			assertPaths(t, files, tc.wantPaths)
+
+			for _, want := range tc.wantProto {
+				if !strings.Contains(files[0].Content, want) {
+					t.Errorf("proto has no %q:\n%s", want, files[0].Content)
+				}
+			}
+			for _, want := range tc.wantConvert {
+				if !strings.Contains(files[1].Content, want) {
+					t.Errorf("convert.go has no %q:\n%s", want, files[1].Content)
+				}
+			}
+			for _, wantNot := range tc.wantNot {
+				if strings.Contains(files[0].Content+files[1].Content, wantNot) {
+					t.Errorf("files have %q", wantNot)
+				}
+			}
+		})
+	}
+}
+
+// protoGoTypes maps the protobuf types of the generated messages to the go types of protoc-gen-go.
+var protoGoTypes = map[string]string{
+	"string":                    "string",
+	"bool":                      "bool",
+	"int64":                     "int64",
+	"int32":                     "int32",
+	"uint64":                    "uint64",
+	"uint32":                    "uint32",
+	"double":                    "float64",
+	"float":                     "float32",
+	"bytes":                     "[]byte",
+	"google.protobuf.Timestamp": "*timestamppb.Timestamp",
+}
+
+// protoFieldRegexp matches a field of a generated message, e.g. optional string name = 2;
+var protoFieldRegexp = regexp.MustCompile(`^\s*(optional |repeated )?(\S+) (\w+) = \d+;$`)
+
+// protoGoStub returns the go structs protoc-gen-go generates for the messages of the proto.
+func protoGoStub(t *testing.T, proto, pbPkg string) string {
+	t.Helper()
+
+	buf := strings.Builder{}
+	buf.WriteString("package " + pbPkg + "\n\nimport \"google.golang.org/protobuf/types/known/timestamppb\"\n\n")
+	buf.WriteString("var _ timestamppb.Timestamp\n")
+	message := false
+	for _, line := range strings.Split(proto, "\n") {
+		if name, ok := strings.CutPrefix(line, "message "); ok {
+			message = true
+			buf.WriteString("\ntype " + strings.TrimSuffix(name, " {") + " struct {\n")
+			continue
+		}
+		if message && line == "}" {
+			message = false
+			buf.WriteString("}\n")
+			continue
+		}
+		match := protoFieldRegexp.FindStringSubmatch(line)
+		if !message || match == nil {
+			continue
+		}
+
+		typ, ok := protoGoTypes[match[2]]
+		if !ok {
+			typ = "*" + match[2]
+		}
+		switch match[1] {
+		case "optional ":
+			typ = "*" + typ
+		case "repeated ":
+			typ = "[]" + typ
+		}
+		buf.WriteString("\t" + protoGoName(match[3]) + " " + typ + "\n")
+	}
+	return buf.String()
+}
+
+func TestProtoFiles_build(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+
+	dir := t.TempDir()
+	w := generator.NewWriter(dir)
+	for _, name := range []string{"model.go", "filter.go"} {
+		code, err := os.ReadFile(filepath.Join("testdata", "user", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(path.Join(BusinessDir, "user", name), string(code)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	pkg, err := EntityPackage(w, path.Join(BusinessDir, "user"), generator.Entity{})
+	if err != nil {
+		t.Fatalf("EntityPackage: %v", err)
+	}
+	files, err := ProtoFiles(pkg, pkg.Entity(), "example.com/shop")
+	if err != nil {
+		t.Fatalf("ProtoFiles: %v", err)
+	}
+
+	// the packages the convert functions import: the protoc-gen-go output of the proto and
+	// stubs of the uuid and the timestamppb packages, so the build needs no network
+	stubs := map[string]string{
+		"go.mod": "module example.com/shop\n\ngo 1.20\n\n" +
+			"require (\n\tgithub.com/google/uuid v1.6.0\n\tgoogle.golang.org/protobuf v1.33.0\n)\n\n" +
+			"replace github.com/google/uuid => ./stubs/uuid\n\n" +
+			"replace google.golang.org/protobuf => ./stubs/protobuf\n",
+		"gen/proto/user/v1/user.pb.go": protoGoStub(t, files[0].Content, "userv1"),
+		"stubs/uuid/go.mod":            "module github.com/google/uuid\n\ngo 1.20\n",
+		"stubs/uuid/uuid.go": "package uuid\n\ntype UUID [16]byte\n\n" +
+			"func Parse(s string) (UUID, error) { return UUID{}, nil }\n\n" +
+			"func (u UUID) String() string { return \"\" }\n",
+		"stubs/protobuf/go.mod": "module google.golang.org/protobuf\n\ngo 1.20\n",
+		"stubs/protobuf/types/known/timestamppb/timestamp.go": "package timestamppb\n\nimport \"time\"\n\n" +
+			"type Timestamp struct{}\n\n" +
+			"func New(t time.Time) *Timestamp { return &Timestamp{} }\n\n" +
+			"func (x *Timestamp) AsTime() time.Time { return time.Time{} }\n",
+	}
+	for _, file := range files[1:] {
+		stubs[file.Path] = file.Content
+	}
+	for name, content := range stubs {
+		if _, err := w.Write(name, content); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "vet", "./business/...", "./handler/...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go vet: %v\n%s\n%s", err, output, files[1].Content)
+	}
+}
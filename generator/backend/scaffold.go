@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// scaffoldDir holds the shared packages the generated entities import.
+const scaffoldDir = "scaffold"
+
+//go:embed scaffold
+var scaffoldFS embed.FS
+
+// Scaffold returns the shared packages the generated entities import, pkg/filter,
+// pkg/apperrors, pkg/auth and pkg/outbox, leaving out the files which already exist in the
+// project. go.mod is created when the project has none.
+func Scaffold(w *generator.Writer, modulePath string) ([]generator.File, error) {
+	files := []generator.File{}
+
+	goMod, err := w.Read("go.mod")
+	if err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+	if goMod == "" {
+		files = append(files, generator.File{Path: "go.mod", Content: "module " + modulePath + "\n\ngo 1.22\n"})
+	}
+
+	scaffold, err := generator.ScaffoldFiles(w, scaffoldFS, scaffoldDir)
+	if err != nil {
+		return nil, fmt.Errorf("generator.ScaffoldFiles: %w", err)
+	}
+
+	return append(files, scaffold...), nil
+}
@@ -0,0 +1,60 @@
+// Package apperrors provides the errors shared by the layers of the application.
+package apperrors
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// list of errors
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrForbidden       = errors.New("forbidden")
+	ErrUnauthenticated = errors.New("unauthenticated")
+)
+
+// ValidationError reports the invalid fields of a request with their messages.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+// NewValidationError creates a new ValidationError for the field.
+func NewValidationError(field, message string) *ValidationError {
+	e := ValidationError{Fields: map[string]string{}}
+	e.Add(field, message)
+	return &e
+}
+
+// Add adds the message of the field. The first message of a field is kept.
+func (e *ValidationError) Add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	if _, ok := e.Fields[field]; !ok {
+		e.Fields[field] = message
+	}
+}
+
+// Err returns the error when it has fields and nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + ": " + e.Fields[field]
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
@@ -0,0 +1,79 @@
+// Package auth provides the claims of the authenticated user and the authorization of the
+// actions on the entities.
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoTenant is returned when the context has no tenant.
+var ErrNoTenant = errors.New("no tenant")
+
+// Claims are the claims of the authenticated user of a request.
+type Claims struct {
+	Subject string
+	Roles   []string
+	// TenantID is the tenant of the user on a multi-tenant platform.
+	TenantID string
+}
+
+// HasRole reports whether the claims have one of the roles.
+func (c Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		for _, r := range c.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type ctxKey int
+
+const claimsKey ctxKey = 1
+
+// WithClaims returns the context with the claims, the authentication middleware sets them
+// after verifying the token of the request.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFrom returns the claims of the context and whether the request is authenticated.
+func ClaimsFrom(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(Claims)
+	return claims, ok
+}
+
+// TenantID returns the tenant of the claims of the context, the multi-tenant entities are
+// scoped to it.
+func TenantID(ctx context.Context) (string, error) {
+	claims, ok := ClaimsFrom(ctx)
+	if !ok || claims.TenantID == "" {
+		return "", ErrNoTenant
+	}
+	return claims.TenantID, nil
+}
+
+// Request is an action of the authenticated user on a resource.
+type Request struct {
+	Claims   Claims
+	Action   string
+	Resource string
+	// Input is the NewX, UpdateX or entity of the action, or the id of a get or delete.
+	Input any
+}
+
+// Authorizer decides whether the request is allowed, e.g. an adapter of OPA or casbin.
+type Authorizer interface {
+	Authorize(ctx context.Context, req Request) (bool, error)
+}
+
+// AuthorizerFunc is a function which implements Authorizer.
+type AuthorizerFunc func(ctx context.Context, req Request) (bool, error)
+
+// Authorize calls the function.
+func (f AuthorizerFunc) Authorize(ctx context.Context, req Request) (bool, error) {
+	return f(ctx, req)
+}
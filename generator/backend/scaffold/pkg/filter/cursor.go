@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cursor is the position of a row in a keyset pagination: the order by field, the value of
+// its column and the id of the row, which breaks the ties of the value. A decoded number
+// value is a json.Number, so an int64 keeps its precision, and binds as its decimal string.
+type Cursor struct {
+	Field string `json:"f"`
+	Value any    `json:"v"`
+	ID    string `json:"id"`
+}
+
+// NewCursor creates a new Cursor.
+func NewCursor(field string, value any, id string) Cursor {
+	return Cursor{
+		Field: field,
+		Value: value,
+		ID:    id,
+	}
+}
+
+// Encode returns the opaque string of the cursor sent to the clients.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes the opaque string of a cursor.
+func DecodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("cursor %q: %w", s, err)
+	}
+
+	var c Cursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return Cursor{}, fmt.Errorf("cursor %q: %w", s, err)
+	}
+	if c.Field == "" || c.ID == "" {
+		return Cursor{}, fmt.Errorf("cursor %q has no field or id", s)
+	}
+	return c, nil
+}
+
+// CursorPage represents the page of a keyset pagination: the rows after the After cursor,
+// or before the Before cursor, in the order of the query.
+type CursorPage struct {
+	After       *Cursor
+	Before      *Cursor
+	RowsPerPage int
+}
+
+// ParseCursorPage parses the after and before cursors and the rows per page of a request.
+// Empty rows per page default to DefaultRowsPerPage.
+func ParseCursorPage(after, before, rowsPerPage string) (CursorPage, error) {
+	page := CursorPage{RowsPerPage: DefaultRowsPerPage}
+
+	if after != "" && before != "" {
+		return CursorPage{}, fmt.Errorf("after and before cursors are exclusive")
+	}
+	if after != "" {
+		c, err := DecodeCursor(after)
+		if err != nil {
+			return CursorPage{}, err
+		}
+		page.After = &c
+	}
+	if before != "" {
+		c, err := DecodeCursor(before)
+		if err != nil {
+			return CursorPage{}, err
+		}
+		page.Before = &c
+	}
+
+	if rowsPerPage != "" {
+		rows, err := strconv.Atoi(rowsPerPage)
+		if err != nil {
+			return CursorPage{}, fmt.Errorf("rows per page %q: %w", rowsPerPage, err)
+		}
+		page.RowsPerPage = rows
+	}
+	if page.RowsPerPage < 1 || page.RowsPerPage > MaxRowsPerPage {
+		return CursorPage{}, fmt.Errorf("rows per page %d must be between 1 and %d", page.RowsPerPage, MaxRowsPerPage)
+	}
+	return page, nil
+}
+
+// Keyset returns the cursor of the page, the operator comparing the (column, id) row values
+// of the rows to the cursor and the direction of the query. A page before a cursor is queried
+// in the reverse direction of the order by, see PageCursors. The cursor is nil for the first
+// page, and an error is returned when it was made for another order by field.
+func (p CursorPage) Keyset(orderBy OrderBy) (*Cursor, string, string, error) {
+	cursor, direction := p.After, strings.ToUpper(orderBy.Direction)
+	if p.Before != nil {
+		cursor = p.Before
+		direction = map[string]string{ASC: DESC, DESC: ASC}[direction]
+	}
+	if cursor != nil && cursor.Field != orderBy.Field {
+		return nil, "", "", fmt.Errorf("cursor of %s used to order by %s", cursor.Field, orderBy.Field)
+	}
+
+	if direction == DESC {
+		return cursor, "<", DESC, nil
+	}
+	return cursor, ">", ASC, nil
+}
+
+// Limit returns the number of rows to query, one more than the page to know whether there
+// is another page.
+func (p CursorPage) Limit() int {
+	return p.RowsPerPage + 1
+}
+
+// Cursors are the opaque cursors of the next and the previous pages, empty when there is no
+// such page.
+type Cursors struct {
+	Next string `json:"next_cursor,omitempty"`
+	Prev string `json:"prev_cursor,omitempty"`
+}
+
+// PageCursors returns the rows of the page in the order by, with the cursors of the next and
+// the previous pages. The rows are the result of the Keyset query limited to Limit rows, the
+// cursor function returns the cursor of a row.
+func PageCursors[T any](page CursorPage, rows []T, cursor func(T) Cursor) ([]T, Cursors) {
+	more := len(rows) > page.RowsPerPage
+	if more {
+		rows = rows[:page.RowsPerPage]
+	}
+	if len(rows) == 0 {
+		return rows, Cursors{}
+	}
+
+	cursors := Cursors{}
+	if page.Before != nil {
+		// the rows before the cursor were queried in the reverse order
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+		cursors.Next = cursor(rows[len(rows)-1]).Encode()
+		if more {
+			cursors.Prev = cursor(rows[0]).Encode()
+		}
+		return rows, cursors
+	}
+
+	if more {
+		cursors.Next = cursor(rows[len(rows)-1]).Encode()
+	}
+	if page.After != nil {
+		cursors.Prev = cursor(rows[0]).Encode()
+	}
+	return rows, cursors
+}
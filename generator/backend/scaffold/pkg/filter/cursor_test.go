@@ -0,0 +1,254 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	testCases := map[string]struct {
+		cursor  string
+		want    Cursor
+		wantErr string
+	}{
+		"string value": {
+			cursor: NewCursor("name", "ada", "1").Encode(),
+			want:   Cursor{Field: "name", Value: "ada", ID: "1"},
+		},
+		"number value": {
+			cursor: NewCursor("price", 9.5, "2").Encode(),
+			want:   Cursor{Field: "price", Value: json.Number("9.5"), ID: "2"},
+		},
+		"int64 value": {
+			cursor: NewCursor("seq", int64(math.MaxInt64), "4").Encode(),
+			want:   Cursor{Field: "seq", Value: json.Number("9223372036854775807"), ID: "4"},
+		},
+		"null value": {
+			cursor: NewCursor("deleted_at", nil, "3").Encode(),
+			want:   Cursor{Field: "deleted_at", ID: "3"},
+		},
+		"not base64": {
+			cursor:  "not a cursor!",
+			wantErr: "illegal base64",
+		},
+		"not json": {
+			cursor:  "bm90IGpzb24",
+			wantErr: "invalid character",
+		},
+		"no id": {
+			cursor:  NewCursor("name", "ada", "").Encode(),
+			wantErr: "has no field or id",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := DecodeCursor(tc.cursor)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("DecodeCursor() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tc.want)
+			}
+			if strings.ContainsAny(tc.cursor, "+/=") {
+				t.Errorf("cursor %q is not url safe", tc.cursor)
+			}
+		})
+	}
+}
+
+func TestParseCursorPage(t *testing.T) {
+	cursor := NewCursor("name", "ada", "1")
+
+	testCases := map[string]struct {
+		after, before, rowsPerPage string
+		want                       CursorPage
+		wantErr                    string
+	}{
+		"first page": {
+			want: CursorPage{RowsPerPage: DefaultRowsPerPage},
+		},
+		"after": {
+			after:       cursor.Encode(),
+			rowsPerPage: "20",
+			want:        CursorPage{After: &cursor, RowsPerPage: 20},
+		},
+		"before": {
+			before: cursor.Encode(),
+			want:   CursorPage{Before: &cursor, RowsPerPage: DefaultRowsPerPage},
+		},
+		"after and before": {
+			after:   cursor.Encode(),
+			before:  cursor.Encode(),
+			wantErr: "exclusive",
+		},
+		"invalid cursor": {
+			after:   "!",
+			wantErr: "cursor",
+		},
+		"rows per page not a number": {
+			rowsPerPage: "ten",
+			wantErr:     "rows per page",
+		},
+		"too many rows per page": {
+			rowsPerPage: strconv.Itoa(MaxRowsPerPage + 1),
+			wantErr:     "must be between",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCursorPage(tc.after, tc.before, tc.rowsPerPage)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ParseCursorPage() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCursorPage: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseCursorPage() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCursorPage_Keyset(t *testing.T) {
+	cursor := NewCursor("name", "ada", "1")
+
+	testCases := map[string]struct {
+		page          CursorPage
+		orderBy       OrderBy
+		wantCursor    *Cursor
+		wantOperator  string
+		wantDirection string
+		wantErr       bool
+	}{
+		"first page":        {orderBy: OrderBy{Field: "name", Direction: ASC}, wantOperator: ">", wantDirection: ASC},
+		"after ascending":   {page: CursorPage{After: &cursor}, orderBy: OrderBy{Field: "name", Direction: ASC}, wantCursor: &cursor, wantOperator: ">", wantDirection: ASC},
+		"after descending":  {page: CursorPage{After: &cursor}, orderBy: OrderBy{Field: "name", Direction: "desc"}, wantCursor: &cursor, wantOperator: "<", wantDirection: DESC},
+		"before ascending":  {page: CursorPage{Before: &cursor}, orderBy: OrderBy{Field: "name", Direction: ASC}, wantCursor: &cursor, wantOperator: "<", wantDirection: DESC},
+		"before descending": {page: CursorPage{Before: &cursor}, orderBy: OrderBy{Field: "name", Direction: DESC}, wantCursor: &cursor, wantOperator: ">", wantDirection: ASC},
+		"another field":     {page: CursorPage{After: &cursor}, orderBy: OrderBy{Field: "email", Direction: ASC}, wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cursor, operator, direction, err := tc.page.Keyset(tc.orderBy)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Keyset: no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Keyset: %v", err)
+			}
+			if cursor != tc.wantCursor || operator != tc.wantOperator || direction != tc.wantDirection {
+				t.Errorf("Keyset() = %v %s %s, want %v %s %s", cursor, operator, direction, tc.wantCursor, tc.wantOperator, tc.wantDirection)
+			}
+		})
+	}
+}
+
+func TestPageCursors(t *testing.T) {
+	cursor := NewCursor("id", 3, "3")
+	rowCursor := func(row int) Cursor {
+		return NewCursor("id", row, strconv.Itoa(row))
+	}
+
+	testCases := map[string]struct {
+		page     CursorPage
+		rows     []int
+		wantRows []int
+		wantNext string
+		wantPrev string
+	}{
+		"empty": {
+			page:     CursorPage{After: &cursor, RowsPerPage: 2},
+			rows:     []int{},
+			wantRows: []int{},
+		},
+		"first page": {
+			page:     CursorPage{RowsPerPage: 2},
+			rows:     []int{1, 2, 3},
+			wantRows: []int{1, 2},
+			wantNext: "2",
+		},
+		"single page": {
+			page:     CursorPage{RowsPerPage: 2},
+			rows:     []int{1},
+			wantRows: []int{1},
+		},
+		"after": {
+			page:     CursorPage{After: &cursor, RowsPerPage: 2},
+			rows:     []int{4, 5, 6},
+			wantRows: []int{4, 5},
+			wantNext: "5",
+			wantPrev: "4",
+		},
+		"last page": {
+			page:     CursorPage{After: &cursor, RowsPerPage: 2},
+			rows:     []int{4},
+			wantRows: []int{4},
+			wantPrev: "4",
+		},
+		"before": {
+			page:     CursorPage{Before: &cursor, RowsPerPage: 1},
+			rows:     []int{2, 1},
+			wantRows: []int{2},
+			wantNext: "2",
+			wantPrev: "2",
+		},
+		"before the first page": {
+			page:     CursorPage{Before: &cursor, RowsPerPage: 2},
+			rows:     []int{2, 1},
+			wantRows: []int{1, 2},
+			wantNext: "2",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rows, cursors := PageCursors(tc.page, tc.rows, rowCursor)
+			if !reflect.DeepEqual(rows, tc.wantRows) {
+				t.Errorf("rows = %v, want %v", rows, tc.wantRows)
+			}
+			if got := cursorID(t, cursors.Next); got != tc.wantNext {
+				t.Errorf("next cursor of %q, want %q", got, tc.wantNext)
+			}
+			if got := cursorID(t, cursors.Prev); got != tc.wantPrev {
+				t.Errorf("prev cursor of %q, want %q", got, tc.wantPrev)
+			}
+		})
+	}
+}
+
+// cursorID returns the id of the encoded cursor, empty for no cursor.
+func cursorID(t *testing.T, s string) string {
+	t.Helper()
+	if s == "" {
+		return ""
+	}
+	c, err := DecodeCursor(s)
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if fmt.Sprint(c.Value) != c.ID {
+		t.Errorf("cursor value %v is not of the row %s", c.Value, c.ID)
+	}
+	return c.ID
+}
@@ -0,0 +1,21 @@
+package filter
+
+// list of order directions
+const (
+	ASC  = "ASC"
+	DESC = "DESC"
+)
+
+// OrderBy represents the field and the direction of the order of a query.
+type OrderBy struct {
+	Field     string
+	Direction string
+}
+
+// NewOrderBy creates a new OrderBy.
+func NewOrderBy(field, direction string) OrderBy {
+	return OrderBy{
+		Field:     field,
+		Direction: direction,
+	}
+}
@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// list of page limits
+const (
+	DefaultRowsPerPage = 10
+	MaxRowsPerPage     = 100
+)
+
+// Page represents the page of a query.
+type Page struct {
+	Number      int
+	RowsPerPage int
+}
+
+// NewPage creates a new Page.
+func NewPage(number, rowsPerPage int) Page {
+	return Page{
+		Number:      number,
+		RowsPerPage: rowsPerPage,
+	}
+}
+
+// ParsePage parses the page number and the rows per page of a request. Empty values
+// default to the first page and DefaultRowsPerPage.
+func ParsePage(number, rowsPerPage string) (Page, error) {
+	page := NewPage(1, DefaultRowsPerPage)
+
+	if number != "" {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return Page{}, fmt.Errorf("page number %q: %w", number, err)
+		}
+		page.Number = n
+	}
+
+	if rowsPerPage != "" {
+		rows, err := strconv.Atoi(rowsPerPage)
+		if err != nil {
+			return Page{}, fmt.Errorf("rows per page %q: %w", rowsPerPage, err)
+		}
+		page.RowsPerPage = rows
+	}
+
+	if err := page.Validate(); err != nil {
+		return Page{}, err
+	}
+	return page, nil
+}
+
+// Validate checks the page number and the rows per page are in range.
+func (p Page) Validate() error {
+	if p.Number < 1 {
+		return fmt.Errorf("page number %d must be at least 1", p.Number)
+	}
+	if p.RowsPerPage < 1 || p.RowsPerPage > MaxRowsPerPage {
+		return fmt.Errorf("rows per page %d must be between 1 and %d", p.RowsPerPage, MaxRowsPerPage)
+	}
+	return nil
+}
+
+// Offset returns the number of rows before the page.
+func (p Page) Offset() int {
+	return (p.Number - 1) * p.RowsPerPage
+}
@@ -0,0 +1,215 @@
+// Package outbox provides the domain events of the entities and the transactional outbox,
+// which persists them in the transaction of the entity writes and relays them to a publisher.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Table is the table of the outbox.
+const Table = "outbox_events"
+
+// Event is a domain event of an entity.
+type Event struct {
+	ID string
+	// Type is the type of the event, e.g. user.created.
+	Type string
+	// Aggregate is the entity of the event, e.g. user.
+	Aggregate   string
+	AggregateID string
+	Payload     json.RawMessage
+	OccurredAt  time.Time
+}
+
+// NewEvent creates a new Event with the payload encoded as JSON.
+func NewEvent(typ, aggregate, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Event{}, fmt.Errorf("newID: %w", err)
+	}
+
+	return Event{
+		ID:          id,
+		Type:        typ,
+		Aggregate:   aggregate,
+		AggregateID: aggregateID,
+		Payload:     data,
+		OccurredAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Execer executes a statement, e.g. the *sql.Tx or the *sqlx.Tx of an entity write.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Insert persists the events in the outbox with the transaction of the entity write, so the
+// events are only published when the write is committed.
+func Insert(ctx context.Context, tx Execer, events ...Event) error {
+	const q = `
+	INSERT INTO outbox_events
+		(id, type, aggregate, aggregate_id, payload, occurred_at)
+	VALUES
+		($1, $2, $3, $4, $5, $6)`
+
+	for _, event := range events {
+		if _, err := tx.ExecContext(ctx, q, event.ID, event.Type, event.Aggregate, event.AggregateID, []byte(event.Payload), event.OccurredAt); err != nil {
+			return fmt.Errorf("ExecContext[%s]: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// Publisher publishes the events to a broker, e.g. Kafka or NATS.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// PublisherFunc is a function which implements Publisher.
+type PublisherFunc func(ctx context.Context, event Event) error
+
+// Publish calls the function.
+func (f PublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Relay publishes the events of the outbox in the order they occurred. An event is published
+// at least once, the consumers ignore the events with an ID they already handled.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	batchSize int
+}
+
+// DefaultBatchSize is the batch size of a Relay created without a positive batch size.
+const DefaultBatchSize = 100
+
+// NewRelay creates a new Relay publishing up to batchSize events at a time,
+// DefaultBatchSize when batchSize is not positive.
+func NewRelay(db *sql.DB, publisher Publisher, batchSize int) *Relay {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		batchSize: batchSize,
+	}
+}
+
+// RelayOnce publishes a batch of unpublished events and marks them published. The events are
+// locked until the batch is committed, concurrent relays skip them. It returns the number of
+// published events, the events after a failed publish are published by the next batch.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("db.BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	events, err := unpublished(ctx, tx, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("unpublished: %w", err)
+	}
+
+	const q = `
+	UPDATE outbox_events
+	SET published_at = $2
+	WHERE id = $1`
+
+	published := 0
+	var publishErr error
+	for _, event := range events {
+		if publishErr = r.publisher.Publish(ctx, event); publishErr != nil {
+			publishErr = fmt.Errorf("publisher.Publish[%s]: %w", event.ID, publishErr)
+			break
+		}
+		if _, err := tx.ExecContext(ctx, q, event.ID, time.Now().UTC()); err != nil {
+			return 0, fmt.Errorf("tx.ExecContext: %w", err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("tx.Commit: %w", err)
+	}
+	return published, publishErr
+}
+
+// Run relays the events every interval until the context is done. The errors of the batches
+// are passed to onError and do not stop the relay.
+func (r *Relay) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.RelayOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+			// a full batch may be followed by more events
+			if err != nil || n < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func unpublished(ctx context.Context, tx *sql.Tx, limit int) ([]Event, error) {
+	const q = `
+	SELECT id, type, aggregate, aggregate_id, payload, occurred_at
+	FROM outbox_events
+	WHERE published_at IS NULL
+	ORDER BY occurred_at, id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("tx.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var event Event
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.Aggregate, &event.AggregateID, &payload, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+	return events, nil
+}
+
+// newID returns a random UUID of version 4.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
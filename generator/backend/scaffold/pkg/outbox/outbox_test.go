@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestNewRelay(t *testing.T) {
+	testCases := map[string]struct {
+		batchSize int
+		want      int
+	}{
+		"batch size": {batchSize: 10, want: 10},
+		"zero":       {batchSize: 0, want: DefaultBatchSize},
+		"negative":   {batchSize: -1, want: DefaultBatchSize},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := NewRelay(nil, nil, tc.batchSize).batchSize; got != tc.want {
+				t.Errorf("batchSize = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	testCases := map[string]struct {
+		payload     any
+		wantPayload string
+		wantErr     bool
+	}{
+		"struct":       {payload: struct{ ID string }{ID: "1"}, wantPayload: `{"ID":"1"}`},
+		"nil":          {payload: nil, wantPayload: "null"},
+		"not encoding": {payload: make(chan int), wantErr: true},
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			event, err := NewEvent("user.created", "user", "1", tc.payload)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("NewEvent: no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewEvent: %v", err)
+			}
+			if string(event.Payload) != tc.wantPayload || !json.Valid(event.Payload) {
+				t.Errorf("Payload = %s, want %s", event.Payload, tc.wantPayload)
+			}
+			if !uuid.MatchString(event.ID) || event.OccurredAt.IsZero() {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package backend
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// sqliteHeader starts the file of a SQLite database.
+const sqliteHeader = "SQLite format 3\x00"
+
+// list of the b-tree page types of a SQLite table
+const (
+	sqliteInteriorPage = 0x05
+	sqliteLeafPage     = 0x0d
+)
+
+// errSQLiteCorrupt is returned when the SQLite file is truncated or malformed.
+var errSQLiteCorrupt = errors.New("corrupt sqlite database file")
+
+// sqliteFile reads the tables of a SQLite database file without the sqlite driver.
+type sqliteFile struct {
+	data     []byte
+	pageSize int
+	// usable is the size of a page without the reserved bytes at the end.
+	usable int
+	// visited are the pages read so far, a page read twice is a loop of a corrupt file.
+	visited map[int]bool
+}
+
+// sqliteSchema returns the CREATE statements of the tables and indexes of a SQLite database
+// file, read from sqlite_master, the b-tree table of the first page. Only UTF-8 databases
+// are supported.
+func sqliteSchema(data []byte) (string, error) {
+	if len(data) < 100 || string(data[:len(sqliteHeader)]) != sqliteHeader {
+		return "", errSQLiteCorrupt
+	}
+	if encoding := binary.BigEndian.Uint32(data[56:60]); encoding > 1 {
+		return "", fmt.Errorf("sqlite text encoding %d is not UTF-8", encoding)
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	f := sqliteFile{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+		visited:  map[int]bool{},
+	}
+	if pageSize < 512 || f.usable < 480 {
+		return "", errSQLiteCorrupt
+	}
+
+	statements := []string{}
+	err := f.walk(1, func(record []any) {
+		// the columns of sqlite_master are type, name, tbl_name, rootpage and sql
+		if len(record) < 5 {
+			return
+		}
+		kind, _ := record[0].(string)
+		name, _ := record[1].(string)
+		sql, _ := record[4].(string)
+		if (kind == "table" || kind == "index") && sql != "" && !strings.HasPrefix(name, "sqlite_") {
+			statements = append(statements, sql+";")
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(statements, "\n"), nil
+}
+
+// walk calls fn with the records of the table b-tree rooted at the page, in rowid order.
+func (f *sqliteFile) walk(page int, fn func(record []any)) error {
+	if page < 1 || page*f.pageSize > len(f.data) || f.visited[page] {
+		return errSQLiteCorrupt
+	}
+	f.visited[page] = true
+
+	data := f.data[(page-1)*f.pageSize : page*f.pageSize]
+	header := 0
+	if page == 1 {
+		// the first page starts with the file header
+		header = 100
+	}
+
+	kind := data[header]
+	cells := int(binary.BigEndian.Uint16(data[header+3 : header+5]))
+	pointers := header + 8
+	if kind == sqliteInteriorPage {
+		pointers = header + 12
+	} else if kind != sqliteLeafPage {
+		return fmt.Errorf("sqlite page %d has type %d: %w", page, kind, errSQLiteCorrupt)
+	}
+	if pointers+2*cells > len(data) {
+		return errSQLiteCorrupt
+	}
+
+	for i := 0; i < cells; i++ {
+		cell := int(binary.BigEndian.Uint16(data[pointers+2*i:]))
+		if cell+4 > len(data) {
+			return errSQLiteCorrupt
+		}
+
+		if kind == sqliteInteriorPage {
+			if err := f.walk(int(binary.BigEndian.Uint32(data[cell:])), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		payload, err := f.payload(data, cell)
+		if err != nil {
+			return err
+		}
+		record, err := sqliteRecord(payload)
+		if err != nil {
+			return err
+		}
+		fn(record)
+	}
+
+	if kind == sqliteInteriorPage {
+		return f.walk(int(binary.BigEndian.Uint32(data[header+8:])), fn)
+	}
+	return nil
+}
+
+// payload returns the payload of the leaf cell, following its overflow pages.
+func (f *sqliteFile) payload(data []byte, cell int) ([]byte, error) {
+	size, n := sqliteVarint(data[cell:])
+	cell += n
+	// the rowid is not needed
+	_, n = sqliteVarint(data[cell:])
+	cell += n
+
+	// the sizes of the payload stored on the page are defined by the file format
+	maxLocal := f.usable - 35
+	local := int(size)
+	if local > maxLocal {
+		minLocal := (f.usable-12)*32/255 - 23
+		local = minLocal + (int(size)-minLocal)%(f.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if n == 0 || cell+local > len(data) {
+		return nil, errSQLiteCorrupt
+	}
+
+	payload := append([]byte{}, data[cell:cell+local]...)
+	if local == int(size) {
+		return payload, nil
+	}
+	if cell+local+4 > len(data) {
+		return nil, errSQLiteCorrupt
+	}
+
+	overflow := int(binary.BigEndian.Uint32(data[cell+local:]))
+	for len(payload) < int(size) {
+		if overflow < 1 || overflow*f.pageSize > len(f.data) || f.visited[overflow] {
+			return nil, errSQLiteCorrupt
+		}
+		f.visited[overflow] = true
+
+		page := f.data[(overflow-1)*f.pageSize : overflow*f.pageSize]
+		content := page[4:f.usable]
+		if rest := int(size) - len(payload); rest < len(content) {
+			content = content[:rest]
+		}
+		payload = append(payload, content...)
+		overflow = int(binary.BigEndian.Uint32(page))
+	}
+	return payload, nil
+}
+
+// sqliteRecord decodes the values of the record: nil, int64, float64, string or []byte.
+func sqliteRecord(payload []byte) ([]any, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || int(headerSize) > len(payload) {
+		return nil, errSQLiteCorrupt
+	}
+
+	values := []any{}
+	body := int(headerSize)
+	for offset := n; offset < int(headerSize); {
+		serial, n := sqliteVarint(payload[offset:int(headerSize)])
+		if n == 0 {
+			return nil, errSQLiteCorrupt
+		}
+		offset += n
+
+		size := 0
+		switch {
+		case serial >= 12:
+			size = int(serial-12) / 2
+		case serial >= 1 && serial <= 4:
+			size = int(serial)
+		case serial == 5:
+			size = 6
+		case serial == 6 || serial == 7:
+			size = 8
+		}
+		if body+size > len(payload) {
+			return nil, errSQLiteCorrupt
+		}
+		value := payload[body : body+size]
+		body += size
+
+		switch {
+		case serial == 0:
+			values = append(values, nil)
+		case serial >= 13 && serial%2 == 1:
+			values = append(values, string(value))
+		case serial >= 12:
+			values = append(values, value)
+		case serial == 8, serial == 9:
+			values = append(values, int64(serial-8))
+		case serial == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		default:
+			integer := int64(0)
+			for i, b := range value {
+				if i == 0 {
+					integer = int64(int8(b))
+					continue
+				}
+				integer = integer<<8 | int64(b)
+			}
+			values = append(values, integer)
+		}
+	}
+	return values, nil
+}
+
+// sqliteVarint decodes the big-endian varint of at most 9 bytes, returning the value and the
+// number of bytes read, 0 when the data is too short.
+func sqliteVarint(data []byte) (uint64, int) {
+	value := uint64(0)
+	for i := 0; i < 9; i++ {
+		if i >= len(data) {
+			return 0, 0
+		}
+		if i == 8 {
+			return value<<8 | uint64(data[i]), 9
+		}
+		value = value<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return value, 9
+}
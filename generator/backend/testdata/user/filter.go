@@ -0,0 +1,8 @@
+package user
+
+// Filter represents a filter for querying users.
+type Filter struct {
+	Email   *string
+	Name    *string
+	Enabled *bool
+}
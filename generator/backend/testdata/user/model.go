@@ -0,0 +1,44 @@
+package user
+
+import (
+	"crypto/sha256"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Password represents a password
+type Password []byte
+
+// Hash converts the password to a hash
+func (p Password) Hash() ([]byte, error) {
+	hash := sha256.Sum256(p)
+	return hash[:], nil
+}
+
+// User represents a user of the system
+type User struct {
+	ID           uuid.UUID
+	Name         string
+	Email        mail.Address
+	PasswordHash []byte
+	Enabled      bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// UpdateUser represents the fields that can be updated
+type UpdateUser struct {
+	ID       uuid.UUID
+	Name     *string
+	Password *Password
+	Enabled  *bool
+}
+
+// NewUser is used to create a new user
+type NewUser struct {
+	Name     string
+	Email    mail.Address
+	Password Password
+}
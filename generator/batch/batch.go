@@ -0,0 +1,481 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"github.com/go-flexi/codegenerator/generator/backend"
+	"github.com/go-flexi/codegenerator/openai"
+)
+
+// LogDir is the directory of the logs of the entities.
+const LogDir = ".codegenerator/logs"
+
+// migrationsLog is the name of the log and of the summary row of the migrations.
+const migrationsLog = "migrations"
+
+// packageFiles are the files of the entity package generated after the model.
+var packageFiles = []string{"filter.go", "order.go", "core.go"}
+
+// Generator generates the files of an entity in one conversation, see backend.Generator.
+type Generator interface {
+	SetWriter(w *generator.Writer)
+	SetEntity(entity generator.Entity)
+	AddFiles(files ...generator.File)
+	FirstCall(modelStruct string) (string, error)
+	PackageCall(fileName string) (string, error)
+	StoreCall() (string, error)
+	HandlerCall(framework backend.Framework) (string, error)
+	CoreTestCall() (string, error)
+	StoreTestCall() (string, error)
+	Usage() openai.Usage
+}
+
+// Runner generates the entities of a manifest.
+type Runner struct {
+	w            *generator.Writer
+	newGenerator func() (Generator, error)
+	modulePath   string
+	title        string
+
+	// mu serializes the writes, the migrations and the service OpenAPI document are
+	// shared by the entities.
+	mu sync.Mutex
+}
+
+// Result is the outcome of the generation of an entity.
+type Result struct {
+	Entity   string
+	Files    []string
+	Usage    openai.Usage
+	Duration time.Duration
+	Err      error
+}
+
+// Summary is the outcome of the generation of the manifest.
+type Summary struct {
+	Results []Result
+	// Migrations is the outcome of the migrations of the entities, nil when no entity has
+	// the migrations layer.
+	Migrations *Result
+	Price      Price
+}
+
+// NewRunner creates a new Runner. Every entity gets its own conversation created with
+// newGenerator.
+func NewRunner(w *generator.Writer, newGenerator func() (Generator, error), modulePath, title string) *Runner {
+	return &Runner{
+		w:            w,
+		newGenerator: newGenerator,
+		modulePath:   modulePath,
+		title:        title,
+	}
+}
+
+// Run generates the entities of the manifest, at most manifest.Concurrency at the same
+// time, and then the migrations of the entities which succeeded. The log of every entity
+// is written to LogDir.
+func (r *Runner) Run(ctx context.Context, manifest Manifest) Summary {
+	results := make([]Result, len(manifest.Entities))
+	sem := make(chan struct{}, manifest.Concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, entity := range manifest.Entities {
+		wg.Add(1)
+		go func(i int, entity Entity) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = Result{Entity: entity.Name, Err: ctx.Err()}
+				return
+			}
+
+			results[i] = r.runEntity(ctx, manifest, entity)
+		}(i, entity)
+	}
+	wg.Wait()
+
+	return Summary{Results: results, Migrations: r.migrations(manifest, results), Price: manifest.Price}
+}
+
+// runEntity generates the layers of the entity and writes its log.
+func (r *Runner) runEntity(ctx context.Context, manifest Manifest, entity Entity) Result {
+	start := time.Now()
+	buf := bytes.Buffer{}
+	logger := log.New(&buf, "", log.LstdFlags)
+
+	result := Result{Entity: entity.Name}
+	g, err := r.newGenerator()
+	if err == nil {
+		g.SetWriter(r.w)
+		result.Files, err = r.generate(ctx, logger, g, manifest, entity)
+		result.Usage = g.Usage()
+	}
+	result.Err = err
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		logger.Printf("failed: %v", err)
+	} else {
+		logger.Printf("done: %d files", len(result.Files))
+	}
+	logger.Printf("tokens: prompt %d, completion %d", result.Usage.PromptTokens, result.Usage.CompletionTokens)
+
+	if _, err := r.write(logger, path.Join(LogDir, entity.Name+".log"), buf.String()); err != nil && result.Err == nil {
+		result.Err = err
+	}
+	return result
+}
+
+// generate generates the layers of the entity in order and returns the written files.
+func (r *Runner) generate(ctx context.Context, logger *log.Logger, g Generator, manifest Manifest, entity Entity) ([]string, error) {
+	entityDir := path.Join(backend.BusinessDir, entity.Name)
+	files := []string{}
+
+	// write writes the generated code of the file
+	write := func(filePath, code string) error {
+		written, err := r.write(logger, filePath, generator.ExtractCode(code))
+		if err != nil {
+			return err
+		}
+		files = append(files, written...)
+		return nil
+	}
+
+	// call generates the file with the call of the generator
+	call := func(filePath string, call func() (string, error)) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		logger.Printf("generating %s", filePath)
+		code, err := call()
+		if err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		return write(filePath, code)
+	}
+
+	// generated writes the files of the deterministic generator
+	generated := func(layer Layer, generate func() ([]generator.File, error)) error {
+		logger.Printf("generating %s", layer)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		layerFiles, err := generate()
+		if err != nil {
+			return fmt.Errorf("%s: %w", layer, err)
+		}
+		for _, file := range layerFiles {
+			written, err := r.writeLocked(logger, file.Path, file.Content)
+			if err != nil {
+				return err
+			}
+			files = append(files, written...)
+		}
+		return nil
+	}
+
+	// permissions writes the permission checks and adds them to the conversation, the core
+	// calls them
+	permissions := func(ir generator.Entity) error {
+		if !entity.Has(LayerPermissions) {
+			return nil
+		}
+		// the permission checks of an entity without a policy allow the authenticated users
+		if entity.Policy == nil {
+			ir.Policy = nil
+		}
+		return generated(LayerPermissions, func() ([]generator.File, error) {
+			permissionFiles, err := backend.PermissionFiles(ir, r.modulePath)
+			if err != nil {
+				return nil, err
+			}
+			g.AddFiles(permissionFiles[0])
+			return permissionFiles, nil
+		})
+	}
+
+	// events writes the domain events and adds them to the conversation, the core emits them
+	events := func(ir generator.Entity) error {
+		if !entity.Has(LayerEvents) {
+			return nil
+		}
+		return generated(LayerEvents, func() ([]generator.File, error) {
+			eventFiles, err := backend.EventFiles(ir, r.modulePath)
+			if err != nil {
+				return nil, err
+			}
+			g.AddFiles(eventFiles[0])
+			return eventFiles, nil
+		})
+	}
+
+	// the core calls the permission checks with a policy, the role model without roles is
+	// the checks of an entity without a policy
+	ir := entity.Entity
+	if ir.Policy == nil && entity.Has(LayerPermissions) {
+		ir.Policy = &generator.Policy{Model: generator.RolePolicy}
+	}
+	g.SetEntity(ir)
+
+	if entity.Has(LayerCore) {
+		modelPrompt := entity.Prompt()
+		err := call(path.Join(entityDir, "model.go"), func() (string, error) { return g.FirstCall(modelPrompt) })
+		if err != nil {
+			return files, err
+		}
+		if err := permissions(ir); err != nil {
+			return files, err
+		}
+		if err := events(ir); err != nil {
+			return files, err
+		}
+		for _, name := range packageFiles {
+			name := name
+			if err := call(path.Join(entityDir, name), func() (string, error) { return g.PackageCall(name) }); err != nil {
+				return files, err
+			}
+		}
+	} else {
+		existing := []generator.File{}
+		for _, name := range append([]string{"model.go"}, packageFiles...) {
+			content, err := r.w.Read(path.Join(entityDir, name))
+			if err != nil {
+				return files, fmt.Errorf("Read: %w", err)
+			}
+			if content != "" {
+				existing = append(existing, generator.File{Path: path.Join(entityDir, name), Content: content})
+			}
+		}
+		if len(existing) == 0 || path.Base(existing[0].Path) != "model.go" {
+			return files, fmt.Errorf("%s has no model.go, generate the core first", entityDir)
+		}
+		g.AddFiles(existing...)
+
+		if len(entity.Fields) == 0 {
+			var err error
+			if ir, err = r.packageEntity(entityDir, ir); err != nil {
+				return files, err
+			}
+			g.SetEntity(ir)
+		}
+		if err := permissions(ir); err != nil {
+			return files, err
+		}
+		if err := events(ir); err != nil {
+			return files, err
+		}
+	}
+
+	storeDir := path.Join("store", entity.Name+"db")
+	llmLayers := []struct {
+		layer    Layer
+		filePath string
+		call     func() (string, error)
+	}{
+		{LayerStore, path.Join(storeDir, entity.Name+"db.go"), g.StoreCall},
+		{LayerHandler, path.Join("handler", entity.Name+"handler", entity.Name+"handler.go"), func() (string, error) { return g.HandlerCall(entity.Framework) }},
+		{LayerCoreTest, path.Join(entityDir, "core_test.go"), g.CoreTestCall},
+		{LayerStoreTest, path.Join(storeDir, entity.Name+"db_test.go"), g.StoreTestCall},
+	}
+	for _, l := range llmLayers {
+		if !entity.Has(l.layer) {
+			continue
+		}
+		if err := call(l.filePath, l.call); err != nil {
+			return files, err
+		}
+	}
+
+	// the protobuf and the OpenAPI documents use the structs of the generated package, or of
+	// the entity of the manifest when the package has no model.go
+	if entity.Has(LayerGRPC) || entity.Has(LayerOpenAPI) {
+		pkg, err := backend.EntityPackage(r.w, entityDir, ir)
+		if err != nil {
+			return files, fmt.Errorf("backend.EntityPackage: %w", err)
+		}
+
+		if entity.Has(LayerGRPC) {
+			err := generated(LayerGRPC, func() ([]generator.File, error) { return backend.ProtoFiles(pkg, ir, r.modulePath) })
+			if err != nil {
+				return files, err
+			}
+		}
+		if entity.Has(LayerOpenAPI) {
+			err := generated(LayerOpenAPI, func() ([]generator.File, error) { return backend.OpenAPIFiles(r.w, pkg, ir, r.title) })
+			if err != nil {
+				return files, err
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// packageEntity returns the entity of the package in the entity directory with the policy
+// and the features of the entity of the manifest, which has no fields.
+func (r *Runner) packageEntity(entityDir string, manifestIR generator.Entity) (generator.Entity, error) {
+	pkg, err := backend.LoadPackage(r.w, entityDir)
+	if err != nil {
+		return generator.Entity{}, fmt.Errorf("backend.LoadPackage: %w", err)
+	}
+
+	// the policy and the features of the manifest apply to the fields of the package
+	ir := pkg.Entity()
+	ir.Policy = manifestIR.Policy
+	ir.SoftDelete = ir.SoftDelete || manifestIR.SoftDelete
+	ir.Audit = ir.Audit || manifestIR.Audit
+	ir.Versioned = ir.Versioned || manifestIR.Versioned
+	ir.MultiTenant = ir.MultiTenant || manifestIR.MultiTenant
+	ir.Events = ir.Events || manifestIR.Events
+	if manifestIR.Pagination != "" {
+		ir.Pagination = manifestIR.Pagination
+	}
+	ir.SetDefaults()
+	return ir, nil
+}
+
+// migrations generates the migrations of the entities which succeeded in one pass, after
+// all of them, so the tables are ordered by their foreign keys across the entities. The log
+// is written to LogDir.
+func (r *Runner) migrations(manifest Manifest, results []Result) *Result {
+	start := time.Now()
+	buf := bytes.Buffer{}
+	logger := log.New(&buf, "", log.LstdFlags)
+
+	result := Result{Entity: migrationsLog}
+	entities := []generator.Entity{}
+	for i, entity := range manifest.Entities {
+		if !entity.Has(LayerMigrations) || results[i].Err != nil {
+			continue
+		}
+		ir := entity.Entity
+		if len(entity.Fields) == 0 {
+			var err error
+			if ir, err = r.packageEntity(path.Join(backend.BusinessDir, entity.Name), ir); err != nil {
+				result.Err = fmt.Errorf("%s: %w", entity.Name, err)
+				break
+			}
+		}
+		entities = append(entities, ir)
+	}
+	if len(entities) == 0 && result.Err == nil {
+		return nil
+	}
+
+	if result.Err == nil {
+		logger.Printf("generating %s", LayerMigrations)
+		files, err := backend.EntitiesMigrations(r.w, entities, manifest.MigrationFormat)
+		if err != nil {
+			result.Err = fmt.Errorf("%s: %w", LayerMigrations, err)
+		}
+		for _, file := range files {
+			if result.Err != nil {
+				break
+			}
+			written, err := r.write(logger, file.Path, file.Content)
+			result.Files = append(result.Files, written...)
+			result.Err = err
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if result.Err != nil {
+		logger.Printf("failed: %v", result.Err)
+	} else {
+		logger.Printf("done: %d files", len(result.Files))
+	}
+	if _, err := r.write(logger, path.Join(LogDir, migrationsLog+".log"), buf.String()); err != nil && result.Err == nil {
+		result.Err = err
+	}
+	return &result
+}
+
+// write writes the file and returns the written paths, the file and its keep report.
+func (r *Runner) write(logger *log.Logger, filePath, content string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.writeLocked(logger, filePath, content)
+}
+
+func (r *Runner) writeLocked(logger *log.Logger, filePath, content string) ([]string, error) {
+	conflicts, err := r.w.Write(filePath, content)
+	if err != nil {
+		return nil, fmt.Errorf("Write: %w", err)
+	}
+
+	written := []string{filePath}
+	for _, conflict := range conflicts {
+		logger.Printf("%s: %s", filePath, conflict.String())
+	}
+	if len(conflicts) > 0 {
+		written = append(written, filePath+".keep")
+	}
+	logger.Printf("wrote %s", filePath)
+
+	return written, nil
+}
+
+// Failed returns the number of the entities and of the migrations which failed.
+func (s Summary) Failed() int {
+	failed := 0
+	for _, result := range s.rows() {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// String returns the table of the results followed by the totals.
+func (s Summary) String() string {
+	buf := bytes.Buffer{}
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTITY\tSTATUS\tFILES\tTOKENS\tCOST\tDURATION")
+
+	total := openai.Usage{}
+	for _, result := range s.rows() {
+		status := "ok"
+		if result.Err != nil {
+			status = "failed"
+		}
+		total = total.Add(result.Usage)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t$%.4f\t%s\n",
+			result.Entity, status, len(result.Files), result.Usage.TotalTokens, s.Price.Cost(result.Usage), result.Duration.Round(time.Millisecond))
+	}
+	tw.Flush()
+
+	for _, result := range s.rows() {
+		if result.Err != nil {
+			fmt.Fprintf(&buf, "\n%s: %v", result.Entity, result.Err)
+		}
+	}
+	if s.Failed() > 0 {
+		buf.WriteString("\n")
+	}
+
+	fmt.Fprintf(&buf, "\n%d succeeded, %d failed, %d tokens (prompt %d, completion %d), $%.4f\n",
+		len(s.rows())-s.Failed(), s.Failed(), total.TotalTokens, total.PromptTokens, total.CompletionTokens, s.Price.Cost(total))
+	return buf.String()
+}
+
+// rows returns the results of the entities followed by the result of the migrations.
+func (s Summary) rows() []Result {
+	if s.Migrations == nil {
+		return s.Results
+	}
+	return append(s.Results[:len(s.Results):len(s.Results)], *s.Migrations)
+}
@@ -0,0 +1,214 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"github.com/go-flexi/codegenerator/generator/backend"
+	"github.com/go-flexi/codegenerator/openai"
+)
+
+// stubFactory creates the stub generators of a run and records how many of them call the
+// model at the same time.
+type stubFactory struct {
+	// failing is the entity whose store call fails.
+	failing string
+
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+	calls      map[string][]string
+}
+
+func (f *stubFactory) newGenerator() (Generator, error) {
+	return &stubGenerator{factory: f}, nil
+}
+
+// stubGenerator returns a file of the package of the entity for every call.
+type stubGenerator struct {
+	factory *stubFactory
+	entity  string
+	usage   openai.Usage
+}
+
+func (g *stubGenerator) call(name string) (string, error) {
+	f := g.factory
+	f.mu.Lock()
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.calls[g.entity] = append(f.calls[g.entity], name)
+	f.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	f.running--
+	f.mu.Unlock()
+
+	g.usage = g.usage.Add(openai.Usage{PromptTokens: 1, TotalTokens: 1})
+	if g.entity == f.failing && name == "store" {
+		return "", errors.New("model is down")
+	}
+	return fmt.Sprintf("package %s\n\n// %s\n", g.entity, name), nil
+}
+
+func (g *stubGenerator) SetWriter(*generator.Writer) {}
+
+func (g *stubGenerator) SetEntity(entity generator.Entity) { g.entity = entity.Name }
+
+func (g *stubGenerator) AddFiles(...generator.File) {}
+
+func (g *stubGenerator) FirstCall(string) (string, error) { return g.call("model.go") }
+
+func (g *stubGenerator) PackageCall(fileName string) (string, error) { return g.call(fileName) }
+
+func (g *stubGenerator) StoreCall() (string, error) { return g.call("store") }
+
+func (g *stubGenerator) HandlerCall(backend.Framework) (string, error) { return g.call("handler") }
+
+func (g *stubGenerator) CoreTestCall() (string, error) { return g.call("coretest") }
+
+func (g *stubGenerator) StoreTestCall() (string, error) { return g.call("storetest") }
+
+func (g *stubGenerator) Usage() openai.Usage { return g.usage }
+
+func TestRunner_Run(t *testing.T) {
+	names := []string{"user", "order", "product", "invoice"}
+	wantCalls := []string{"model.go", "filter.go", "order.go", "core.go", "store", "handler", "coretest"}
+
+	testCases := map[string]struct {
+		concurrency int
+		failing     string
+		wantFailed  int
+	}{
+		"sequential":     {concurrency: 1},
+		"bounded":        {concurrency: 2},
+		"failing entity": {concurrency: 2, failing: "order", wantFailed: 1},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := generator.NewWriter(t.TempDir())
+			factory := &stubFactory{failing: tc.failing, calls: map[string][]string{}}
+			manifest := Manifest{Concurrency: tc.concurrency}
+			// the layers are listed out of order, they are generated in the order of layers
+			for _, name := range names {
+				manifest.Entities = append(manifest.Entities, Entity{
+					Entity: generator.Entity{Name: name, Fields: []generator.Field{{Name: "ID", Type: "uuid.UUID", PrimaryKey: true}}},
+					Layers: []Layer{LayerCoreTest, LayerHandler, LayerStore, LayerPermissions, LayerCore},
+				})
+			}
+
+			summary := NewRunner(w, factory.newGenerator, "example.com/shop", "shop").Run(context.Background(), manifest)
+
+			if factory.maxRunning != tc.concurrency {
+				t.Errorf("entities at the same time = %d, want %d", factory.maxRunning, tc.concurrency)
+			}
+			if summary.Migrations != nil {
+				t.Errorf("Migrations = %+v, want nil", summary.Migrations)
+			}
+
+			for i, result := range summary.Results {
+				entity := names[i]
+				if result.Entity != entity {
+					t.Fatalf("Results[%d].Entity = %s, want %s", i, result.Entity, entity)
+				}
+
+				log, err := w.Read(path.Join(LogDir, entity+".log"))
+				if err != nil {
+					t.Fatalf("Read: %v", err)
+				}
+
+				calls := wantCalls
+				if entity == tc.failing {
+					calls = wantCalls[:5]
+					if result.Err == nil || !strings.Contains(result.Err.Error(), "model is down") {
+						t.Errorf("%s error = %v, want the store error", entity, result.Err)
+					}
+					if !strings.Contains(log, "failed: ") {
+						t.Errorf("%s log has no failure:\n%s", entity, log)
+					}
+				} else {
+					if result.Err != nil {
+						t.Errorf("%s error = %v, want nil", entity, result.Err)
+					}
+					// the permission checks and their tests are written besides the calls
+					if !strings.Contains(log, fmt.Sprintf("done: %d files", len(calls)+2)) {
+						t.Errorf("%s log has no done:\n%s", entity, log)
+					}
+				}
+				if !strings.Contains(log, "wrote business/"+entity+"/permission.go") {
+					t.Errorf("%s log has no permission checks:\n%s", entity, log)
+				}
+
+				if got := fmt.Sprint(factory.calls[entity]); got != fmt.Sprint(calls) {
+					t.Errorf("%s calls = %s, want %s", entity, got, fmt.Sprint(calls))
+				}
+				if result.Usage.PromptTokens != len(calls) {
+					t.Errorf("%s prompt tokens = %d, want %d", entity, result.Usage.PromptTokens, len(calls))
+				}
+				// the log of an entity has only its own files
+				for _, other := range names {
+					if other != entity && strings.Contains(log, "business/"+other+"/") {
+						t.Errorf("%s log has the files of %s:\n%s", entity, other, log)
+					}
+				}
+			}
+
+			if got := summary.Failed(); got != tc.wantFailed {
+				t.Errorf("Failed() = %d, want %d", got, tc.wantFailed)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	testCases := map[string]struct {
+		summary    Summary
+		wantFailed int
+		want       []string
+	}{
+		"no migrations": {
+			summary: Summary{Results: []Result{{Entity: "user", Files: []string{"business/user/model.go"}}}},
+			want:    []string{"user    ok      1", "1 succeeded, 0 failed"},
+		},
+		"migrations": {
+			summary: Summary{
+				Results:    []Result{{Entity: "user"}, {Entity: "order"}},
+				Migrations: &Result{Entity: migrationsLog, Files: []string{"migrations/00001_create_users_table.sql"}},
+			},
+			want: []string{"migrations  ok      1", "3 succeeded, 0 failed"},
+		},
+		"failed migrations": {
+			summary: Summary{
+				Results:    []Result{{Entity: "user"}},
+				Migrations: &Result{Entity: migrationsLog, Err: errors.New("cycle")},
+			},
+			wantFailed: 1,
+			want:       []string{"migrations: cycle", "1 succeeded, 1 failed"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.summary.Failed(); got != tc.wantFailed {
+				t.Errorf("Failed() = %d, want %d", got, tc.wantFailed)
+			}
+			got := tc.summary.String()
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("String() has no %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
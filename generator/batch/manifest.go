@@ -0,0 +1,158 @@
+package batch
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"github.com/go-flexi/codegenerator/generator/backend"
+	"github.com/go-flexi/codegenerator/openai"
+)
+
+// Layer is a part of the code generated for an entity.
+type Layer string
+
+// list of layers in the order they are generated
+const (
+	LayerCore        Layer = "core"
+	LayerPermissions Layer = "permissions"
+	LayerEvents      Layer = "events"
+	LayerStore       Layer = "store"
+	LayerHandler     Layer = "handler"
+	LayerCoreTest    Layer = "coretest"
+	LayerStoreTest   Layer = "storetest"
+	LayerMigrations  Layer = "migrations"
+	LayerGRPC        Layer = "grpc"
+	LayerOpenAPI     Layer = "openapi"
+)
+
+// layers are the known layers in the order they are generated.
+var layers = []Layer{LayerCore, LayerPermissions, LayerEvents, LayerStore, LayerHandler, LayerCoreTest, LayerStoreTest, LayerMigrations, LayerGRPC, LayerOpenAPI}
+
+// defaultLayers are generated when an entity lists no layers.
+var defaultLayers = []Layer{LayerCore, LayerPermissions, LayerStore, LayerHandler, LayerMigrations}
+
+// defaultConcurrency is the number of entities generated at the same time.
+const defaultConcurrency = 4
+
+// Manifest lists the entities to generate.
+type Manifest struct {
+	Concurrency     int                     `yaml:"concurrency,omitempty"`
+	MigrationFormat backend.MigrationFormat `yaml:"migration_format,omitempty"`
+	Framework       backend.Framework       `yaml:"framework,omitempty"`
+	Price           Price                   `yaml:"price,omitempty"`
+	// MultiTenant turns on the multi-tenancy of every entity.
+	MultiTenant bool     `yaml:"multi_tenant,omitempty"`
+	Entities    []Entity `yaml:"entities"`
+}
+
+// Price is the price in dollars of 1K prompt and completion tokens.
+type Price struct {
+	Prompt     float64 `yaml:"prompt"`
+	Completion float64 `yaml:"completion"`
+}
+
+// Cost returns the cost in dollars of the usage.
+func (p Price) Cost(usage openai.Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Prompt + float64(usage.CompletionTokens)*p.Completion) / 1000
+}
+
+// Entity is an entity of the manifest with the layers to generate. The fields are only
+// needed to generate the core, the other layers use the entity package of the project when
+// the core is not generated.
+type Entity struct {
+	generator.Entity `yaml:",inline"`
+	Layers           []Layer           `yaml:"layers,omitempty"`
+	Framework        backend.Framework `yaml:"framework,omitempty"`
+}
+
+// LoadManifest parses and validates the YAML manifest, filling in the defaults.
+func LoadManifest(data []byte) (Manifest, error) {
+	manifest := Manifest{}
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("yaml.UnmarshalStrict: %w", err)
+	}
+
+	if manifest.Concurrency <= 0 {
+		manifest.Concurrency = defaultConcurrency
+	}
+	if manifest.MigrationFormat == "" {
+		manifest.MigrationFormat = backend.GolangMigrate
+	}
+	if manifest.Framework == "" {
+		manifest.Framework = backend.NetHTTP
+	}
+	if err := manifest.MigrationFormat.Validate(); err != nil {
+		return Manifest{}, fmt.Errorf("migration_format: %w", err)
+	}
+	if err := manifest.Framework.Validate(); err != nil {
+		return Manifest{}, fmt.Errorf("framework: %w", err)
+	}
+
+	names := map[string]bool{}
+	for i := range manifest.Entities {
+		entity := &manifest.Entities[i]
+		entity.MultiTenant = entity.MultiTenant || manifest.MultiTenant
+		entity.SetDefaults()
+		if err := entity.validate(); err != nil {
+			return Manifest{}, fmt.Errorf("entities[%d]: %w", i, err)
+		}
+		if names[entity.Name] {
+			return Manifest{}, fmt.Errorf("entities[%d]: duplicate entity %s", i, entity.Name)
+		}
+		names[entity.Name] = true
+
+		if len(entity.Layers) == 0 {
+			entity.Layers = defaultLayers
+		}
+		// the events of an entity are generated by the events layer
+		if entity.Events && !entity.Has(LayerEvents) {
+			entity.Layers = append(entity.Layers[:len(entity.Layers):len(entity.Layers)], LayerEvents)
+		}
+		entity.Events = entity.Has(LayerEvents)
+		if entity.Framework == "" {
+			entity.Framework = manifest.Framework
+		}
+	}
+
+	return manifest, nil
+}
+
+// Has reports whether the layer is generated for the entity.
+func (e Entity) Has(layer Layer) bool {
+	for _, l := range e.Layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Entity) validate() error {
+	if err := e.Entity.Validate(); err != nil {
+		return err
+	}
+
+	for _, layer := range e.Layers {
+		known := false
+		for _, l := range layers {
+			known = known || l == layer
+		}
+		if !known {
+			return fmt.Errorf("%s: unknown layer %q", e.Name, layer)
+		}
+	}
+
+	if e.Framework != "" {
+		if err := e.Framework.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", e.Name, err)
+		}
+	}
+
+	if (len(e.Layers) == 0 || e.Has(LayerCore)) && len(e.Fields) == 0 {
+		return fmt.Errorf("%s: the core needs the fields", e.Name)
+	}
+
+	return nil
+}
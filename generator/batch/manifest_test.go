@@ -0,0 +1,69 @@
+package batch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator/backend"
+)
+
+func TestLoadManifest(t *testing.T) {
+	testCases := map[string]struct {
+		manifest      string
+		wantErr       string
+		wantFormat    backend.MigrationFormat
+		wantFramework backend.Framework
+	}{
+		"defaults": {
+			manifest:      "entities:\n- name: tag\n  layers: [store]\n",
+			wantFormat:    backend.GolangMigrate,
+			wantFramework: backend.NetHTTP,
+		},
+		"entity framework": {
+			manifest:      "migration_format: goose\nentities:\n- name: tag\n  layers: [handler]\n  framework: chi\n",
+			wantFormat:    backend.Goose,
+			wantFramework: backend.Chi,
+		},
+		"unknown migration format": {
+			manifest: "migration_format: gooose\nentities:\n- name: tag\n  layers: [store]\n",
+			wantErr:  "migration_format",
+		},
+		"unknown framework": {
+			manifest: "framework: gin\nentities:\n- name: tag\n  layers: [store]\n",
+			wantErr:  "framework",
+		},
+		"unknown entity framework": {
+			manifest: "entities:\n- name: tag\n  layers: [handler]\n  framework: fiber\n",
+			wantErr:  "entities[0]",
+		},
+		"unknown layer": {
+			manifest: "entities:\n- name: tag\n  layers: [cache]\n",
+			wantErr:  "unknown layer",
+		},
+		"duplicate entity": {
+			manifest: "entities:\n- name: tag\n  layers: [store]\n- name: tag\n  layers: [store]\n",
+			wantErr:  "duplicate",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			manifest, err := LoadManifest([]byte(tc.manifest))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("LoadManifest() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadManifest: %v", err)
+			}
+			if manifest.MigrationFormat != tc.wantFormat {
+				t.Errorf("MigrationFormat = %s, want %s", manifest.MigrationFormat, tc.wantFormat)
+			}
+			if got := manifest.Entities[0].Framework; got != tc.wantFramework {
+				t.Errorf("Framework = %s, want %s", got, tc.wantFramework)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package batch
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// list of schema extensions
+const (
+	// FilterableExtension marks a property the entity is filtered by, with true or with the
+	// list of its filter operators, e.g. [eq, contains].
+	FilterableExtension = "x-filterable"
+	// SortableExtension marks a property the entity is ordered by.
+	SortableExtension = "x-sortable"
+)
+
+// schemaFormats maps the string formats to go types.
+var schemaFormats = map[string]string{
+	"email":     "mail.Address",
+	"uuid":      "uuid.UUID",
+	"date-time": "time.Time",
+	"date":      "time.Time",
+	"byte":      "[]byte",
+	"binary":    "[]byte",
+}
+
+// SchemaEntities returns the entities of the object schemas of a JSON Schema or OpenAPI
+// document, in JSON or YAML. The schemas are the components of an OpenAPI document, the
+// $defs or definitions of a JSON Schema, or the document itself named by its title. Only
+// the named schemas are converted when names are given. The required properties are not
+// nullable, the properties marked with x-filterable and x-sortable are filterable and
+// sortable, x-filterable may list the filter operators, the readOnly properties are not
+// updatable and the writeOnly properties are sensitive. The length, pattern, enum and range
+// keywords are the validation rules of the properties. A $ref to an object schema is a
+// belongs_to relation with the foreign key of the id of the schema, and an array of them is a
+// has_many relation.
+func SchemaEntities(data []byte, names ...string) ([]generator.Entity, error) {
+	// a MapSlice keeps the order of the properties
+	ordered := yaml.MapSlice{}
+	if err := yaml.Unmarshal(data, &ordered); err != nil {
+		return nil, fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+	doc := schemaMap(ordered)
+
+	var schemas interface{}
+	switch {
+	case schemaMap(doc["components"])["schemas"] != nil:
+		schemas = schemaMap(doc["components"])["schemas"]
+	case doc["$defs"] != nil:
+		schemas = doc["$defs"]
+	case doc["definitions"] != nil:
+		schemas = doc["definitions"]
+	default:
+		title, _ := doc["title"].(string)
+		if title == "" {
+			return nil, fmt.Errorf("schema has no title, components, $defs or definitions")
+		}
+		schemas = yaml.MapSlice{{Key: title, Value: ordered}}
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	schemaNames := []string{}
+	for _, name := range schemaKeys(schemas) {
+		if len(wanted) == 0 || wanted[name] {
+			schemaNames = append(schemaNames, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := schemaMap(schemas)[name]; !ok {
+			return nil, fmt.Errorf("schema %s not found", name)
+		}
+	}
+
+	entities := []generator.Entity{}
+	for _, name := range schemaNames {
+		schema := schemaMap(schemaMap(schemas)[name])
+		if typ, _ := schemaType(schema); typ != "object" || schema["properties"] == nil {
+			if len(wanted) > 0 {
+				return nil, fmt.Errorf("schema %s is not an object", name)
+			}
+			continue
+		}
+		entity, err := schemaEntity(name, schema, schemaMap(schemas))
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+	}
+
+	return entities, nil
+}
+
+// schemaEntity converts the object schema to an entity, the $ref of its properties point to
+// the schemas.
+func schemaEntity(name string, schema, schemas map[string]interface{}) (generator.Entity, error) {
+	structName := generator.CamelCase(generator.SnakeCase(name))
+	entity := generator.NewEntity(strings.ToLower(structName))
+	entity.Struct = structName
+	entity.Table = generator.Plural(generator.SnakeCase(structName))
+
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, property := range list {
+			required[fmt.Sprint(property)] = true
+		}
+	}
+
+	columns := map[string]bool{}
+	for _, property := range schemaKeys(schema["properties"]) {
+		columns[generator.SnakeCase(property)] = true
+	}
+
+	properties := schemaMap(schema["properties"])
+	for _, property := range schemaKeys(schema["properties"]) {
+		propertySchema := schemaMap(properties[property])
+		fieldName := generator.CamelCase(generator.SnakeCase(property))
+
+		relation, key, err := schemaRelation(fieldName, propertySchema, schemas)
+		if err != nil {
+			return generator.Entity{}, fmt.Errorf("%s.%s: %w", name, property, err)
+		}
+		if relation.Kind != "" {
+			entity.Relations = append(entity.Relations, relation)
+			// the foreign key is a property of its own or the id of the related schema
+			if relation.Kind == generator.HasMany || columns[generator.SnakeCase(relation.Field)] {
+				continue
+			}
+			fieldName = relation.Field
+			propertySchema = map[string]interface{}{"type": key["type"], "format": key["format"], "readOnly": propertySchema["readOnly"]}
+		}
+		typ, nullable := schemaGoType(propertySchema, schemas)
+
+		field := generator.Field{
+			Name:       fieldName,
+			Type:       typ,
+			Nullable:   nullable || !required[property],
+			Filterable: propertySchema[FilterableExtension] == true,
+			Sortable:   propertySchema[SortableExtension] == true,
+			Updatable:  propertySchema["readOnly"] != true,
+			Sensitive:  propertySchema["writeOnly"] == true,
+			Validate:   schemaRules(propertySchema, typ),
+		}
+		if operators, ok := propertySchema[FilterableExtension].([]interface{}); ok {
+			for _, operator := range operators {
+				field.Operators = append(field.Operators, generator.Operator(fmt.Sprint(operator)))
+			}
+			field.Filterable = len(field.Operators) > 0
+		}
+		field.Column = generator.SnakeCase(field.Name)
+		field.PrimaryKey = field.Name == "ID"
+		field.Sortable = field.Sortable || field.PrimaryKey
+		field.Immutable = field.PrimaryKey || field.Name == "CreatedAt"
+		field.Updatable = field.Updatable && !field.Immutable
+		entity.Fields = append(entity.Fields, field)
+	}
+
+	return entity, nil
+}
+
+// schemaRelation returns the relation of a property which is a $ref to an object schema,
+// belongs_to with the id property of the related schema, or an array of them, has_many. The
+// relation has no kind when the property is not an object.
+func schemaRelation(fieldName string, schema, schemas map[string]interface{}) (generator.Relation, map[string]interface{}, error) {
+	kind := generator.BelongsTo
+	if typ, _ := schemaType(schema); typ == "array" {
+		kind = generator.HasMany
+		schema = schemaMap(schema["items"])
+	}
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return generator.Relation{}, nil, nil
+	}
+
+	refName := ref[strings.LastIndex(ref, "/")+1:]
+	if _, ok := schemas[refName]; !ok {
+		return generator.Relation{}, nil, fmt.Errorf("$ref %s is not a schema of the document", ref)
+	}
+	related := schemaMap(schemas[refName])
+	if typ, _ := schemaType(related); typ != "object" {
+		return generator.Relation{}, nil, nil
+	}
+
+	structName := generator.CamelCase(generator.SnakeCase(refName))
+	relation := generator.Relation{
+		Name:   fieldName,
+		Kind:   kind,
+		Entity: strings.ToLower(structName),
+		Table:  generator.Plural(generator.SnakeCase(structName)),
+	}
+	if kind == generator.HasMany {
+		return relation, nil, nil
+	}
+
+	key, ok := schemaMap(related["properties"])["id"]
+	if !ok {
+		return generator.Relation{}, nil, fmt.Errorf("$ref %s has no id property for the foreign key", ref)
+	}
+	relation.Field = fieldName + "ID"
+	return relation, schemaMap(key), nil
+}
+
+// schemaRules returns the validation rules of the keywords of the schema, or nil when it
+// has none.
+func schemaRules(schema map[string]interface{}, goType string) *generator.Rules {
+	rules := generator.Rules{}
+	number := func(keyword string) *float64 {
+		switch n := schema[keyword].(type) {
+		case int:
+			f := float64(n)
+			return &f
+		case float64:
+			return &n
+		}
+		return nil
+	}
+	length := func(keyword string) *int {
+		if n := number(keyword); n != nil {
+			l := int(*n)
+			return &l
+		}
+		return nil
+	}
+
+	rules.MinLength = length("minLength")
+	rules.MaxLength = length("maxLength")
+	rules.Min = number("minimum")
+	rules.Max = number("maximum")
+	rules.Pattern, _ = schema["pattern"].(string)
+	if enum, ok := schema["enum"].([]interface{}); ok && goType == "string" {
+		for _, value := range enum {
+			if value != nil {
+				rules.Enum = append(rules.Enum, fmt.Sprint(value))
+			}
+		}
+	}
+
+	if rules.String() == "" {
+		return nil
+	}
+	return &rules
+}
+
+// schemaGoType returns the go type of the schema and whether it is nullable. A $ref has the
+// type of the schema it points to.
+func schemaGoType(schema, schemas map[string]interface{}) (string, bool) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if related, ok := schemas[ref[strings.LastIndex(ref, "/")+1:]]; ok {
+			return schemaGoType(schemaMap(related), schemas)
+		}
+		return "map[string]any", false
+	}
+
+	typ, nullable := schemaType(schema)
+	if schema["nullable"] == true {
+		nullable = true
+	}
+	format, _ := schema["format"].(string)
+
+	switch typ {
+	case "string":
+		if goType, ok := schemaFormats[format]; ok {
+			return goType, nullable
+		}
+		return "string", nullable
+	case "integer":
+		switch format {
+		case "int32":
+			return "int32", nullable
+		case "int64":
+			return "int64", nullable
+		}
+		return "int", nullable
+	case "number":
+		if format == "float" {
+			return "float32", nullable
+		}
+		return "float64", nullable
+	case "boolean":
+		return "bool", nullable
+	case "array":
+		itemType, _ := schemaGoType(schemaMap(schema["items"]), schemas)
+		return "[]" + itemType, nullable
+	}
+	return "map[string]any", nullable
+}
+
+// schemaType returns the type of the schema. A JSON Schema type list with null is nullable.
+func schemaType(schema map[string]interface{}) (string, bool) {
+	switch typ := schema["type"].(type) {
+	case string:
+		return typ, false
+	case []interface{}:
+		name, nullable := "", false
+		for _, t := range typ {
+			if t == "null" {
+				nullable = true
+			} else if name == "" {
+				name = fmt.Sprint(t)
+			}
+		}
+		return name, nullable
+	}
+	if schema["properties"] != nil {
+		return "object", false
+	}
+	return "", false
+}
+
+// schemaMap returns the value as a map with string keys.
+func schemaMap(value interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if slice, ok := value.(yaml.MapSlice); ok {
+		for _, item := range slice {
+			m[fmt.Sprint(item.Key)] = item.Value
+		}
+	}
+	return m
+}
+
+// schemaKeys returns the keys of the map in the order of the document.
+func schemaKeys(value interface{}) []string {
+	keys := []string{}
+	if slice, ok := value.(yaml.MapSlice); ok {
+		for _, item := range slice {
+			keys = append(keys, fmt.Sprint(item.Key))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,106 @@
+package batch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+const orderSchemas = `
+components:
+  schemas:
+    Status:
+      type: string
+      enum: [pending, paid]
+    Customer:
+      type: object
+      properties:
+        id: {type: string, format: uuid, readOnly: true}
+    OrderItem:
+      type: object
+      properties:
+        id: {type: integer, format: int64}
+    Order:
+      type: object
+      required: [id, customer]
+      properties:
+        id: {type: string, format: uuid}
+        customer: {$ref: '#/components/schemas/Customer'}
+        status: {$ref: '#/components/schemas/Status'}
+        items:
+          type: array
+          items: {$ref: '#/components/schemas/OrderItem'}
+`
+
+func TestSchemaEntities_ref(t *testing.T) {
+	testCases := map[string]struct {
+		schema        string
+		wantErr       string
+		wantFields    []string
+		wantRelations []generator.Relation
+	}{
+		"belongs to and has many": {
+			schema:     orderSchemas,
+			wantFields: []string{"ID uuid.UUID", "CustomerID uuid.UUID", "Status *string"},
+			wantRelations: []generator.Relation{
+				{Name: "Customer", Kind: generator.BelongsTo, Entity: "customer", Table: "customers", Field: "CustomerID"},
+				{Name: "Items", Kind: generator.HasMany, Entity: "orderitem", Table: "order_items"},
+			},
+		},
+		"foreign key property": {
+			schema: strings.Replace(orderSchemas, "        customer:",
+				"        customer_id: {type: string, format: uuid, x-filterable: true}\n        customer:", 1),
+			wantFields: []string{"ID uuid.UUID", "CustomerID *uuid.UUID", "Status *string"},
+			wantRelations: []generator.Relation{
+				{Name: "Customer", Kind: generator.BelongsTo, Entity: "customer", Table: "customers", Field: "CustomerID"},
+				{Name: "Items", Kind: generator.HasMany, Entity: "orderitem", Table: "order_items"},
+			},
+		},
+		"unknown ref": {
+			schema:  strings.Replace(orderSchemas, "schemas/Customer'", "schemas/Client'", 1),
+			wantErr: "Order.customer: $ref #/components/schemas/Client",
+		},
+		"ref without id": {
+			schema:  strings.Replace(orderSchemas, "        id: {type: string, format: uuid, readOnly: true}", "        name: {type: string}", 1),
+			wantErr: "Order.customer: $ref #/components/schemas/Customer has no id property",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			entities, err := SchemaEntities([]byte(tc.schema), "Order")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("SchemaEntities() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SchemaEntities: %v", err)
+			}
+
+			entity := entities[0]
+			fields := []string{}
+			for _, field := range entity.Fields {
+				typ := field.Type
+				if field.Nullable {
+					typ = "*" + typ
+				}
+				fields = append(fields, field.Name+" "+typ)
+			}
+			if fmt.Sprint(fields) != fmt.Sprint(tc.wantFields) {
+				t.Errorf("Fields = %v, want %v", fields, tc.wantFields)
+			}
+			if fmt.Sprint(entity.Relations) != fmt.Sprint(tc.wantRelations) {
+				t.Errorf("Relations = %+v, want %+v", entity.Relations, tc.wantRelations)
+			}
+
+			entity.SetDefaults()
+			if err := entity.Validate(); err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// codeFence starts and ends a markdown code block.
+const codeFence = "```"
+
+// ExtractCode returns the code of the first markdown code block in the content,
+// or the content itself if it has no code block.
+func ExtractCode(content string) string {
+	start := strings.Index(content, codeFence)
+	if start == -1 {
+		return content
+	}
+
+	code := content[start+len(codeFence):]
+	if newLine := strings.Index(code, "\n"); newLine != -1 {
+		code = code[newLine+1:]
+	}
+	if end := strings.Index(code, codeFence); end != -1 {
+		code = code[:end]
+	}
+	return code
+}
+
+// PackageName returns the package name of the go code.
+func PackageName(code string) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", ExtractCode(code), parser.PackageClauseOnly)
+	if err != nil {
+		return "", fmt.Errorf("parser.ParseFile: %w", err)
+	}
+	return file.Name.Name, nil
+}
@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"path"
+	"strings"
+)
+
+// Files is the last code of the files of a conversation by their path.
+type Files map[string]string
+
+// File returns the code of the file.
+func (f Files) File(filePath string) (string, bool) {
+	code, ok := f[filePath]
+	return code, ok
+}
+
+// AddFiles adds the existing files of the project to the messages as a user message after
+// the header. The entity is returned from the package of model.go, or an empty string when
+// the files have no model.go.
+func (f Files) AddFiles(m *Messages, header string, files ...File) string {
+	entity := ""
+	buf := strings.Builder{}
+	buf.WriteString(header + "\n")
+	for _, file := range files {
+		buf.WriteString("\n" + file.Path + "\n" + file.Content + "\n")
+		f[file.Path] = file.Content
+
+		if entity != "" || path.Base(file.Path) != "model.go" {
+			continue
+		}
+		if name, err := PackageName(file.Content); err == nil {
+			entity = name
+		}
+	}
+
+	m.AddUserMessage(buf.String())
+	return entity
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operator is an operator of the filter of a field.
+type Operator string
+
+// list of filter operators
+const (
+	// EqualOperator filters by the value of the field, e.g. Email.
+	EqualOperator Operator = "eq"
+	// RangeOperator filters from a start value included to an end value excluded, e.g.
+	// StartCreatedAt and EndCreatedAt.
+	RangeOperator Operator = "range"
+	// InOperator filters by a list of values, e.g. IDs.
+	InOperator Operator = "in"
+	// ContainsOperator filters the strings containing a value, ignoring the case, e.g. NameContains.
+	ContainsOperator Operator = "contains"
+	// NullOperator filters the null or the set values of a nullable field, e.g. DeletedAtIsNull.
+	NullOperator Operator = "null"
+)
+
+// FilterField is a field of the Filter of an entity.
+type FilterField struct {
+	// Name is the name of the Filter field, e.g. StartCreatedAt.
+	Name string
+	// Type is the go type of the Filter field, e.g. *time.Time or []uuid.UUID.
+	Type string
+	// Param is the query string parameter of the handlers, e.g. start_created_at.
+	Param string
+	// Field is the entity field which is filtered.
+	Field    Field
+	Operator Operator
+}
+
+// FilterOperators returns the operators of a filterable field, the equal operator by default.
+func (f Field) FilterOperators() []Operator {
+	if len(f.Operators) > 0 {
+		return f.Operators
+	}
+	if f.Filterable {
+		return []Operator{EqualOperator}
+	}
+	return nil
+}
+
+// FilterFields returns the fields of the Filter of the filterable fields in the order of the
+// fields and of their operators.
+func (e Entity) FilterFields() []FilterField {
+	fields := []FilterField{}
+	for _, field := range e.Fields {
+		for _, operator := range field.FilterOperators() {
+			fields = append(fields, filterFields(field, operator)...)
+		}
+	}
+	return fields
+}
+
+// HasFilterOperators reports whether a field is filtered by another operator than the equal
+// operator.
+func (e Entity) HasFilterOperators() bool {
+	for _, field := range e.Fields {
+		for _, operator := range field.FilterOperators() {
+			if operator != EqualOperator {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterFieldName returns the name of the Filter field of the operator on the field.
+func FilterFieldName(field string, operator Operator) string {
+	switch operator {
+	case InOperator:
+		return Plural(field)
+	case ContainsOperator:
+		return field + "Contains"
+	case NullOperator:
+		return field + "IsNull"
+	}
+	return field
+}
+
+func filterFields(field Field, operator Operator) []FilterField {
+	filterField := func(name, typ string) FilterField {
+		return FilterField{Name: name, Type: typ, Param: SnakeCase(name), Field: field, Operator: operator}
+	}
+
+	switch operator {
+	case RangeOperator:
+		return []FilterField{
+			filterField("Start"+field.Name, "*"+field.Type),
+			filterField("End"+field.Name, "*"+field.Type),
+		}
+	case InOperator:
+		return []FilterField{filterField(FilterFieldName(field.Name, operator), "[]"+field.Type)}
+	case ContainsOperator:
+		return []FilterField{filterField(FilterFieldName(field.Name, operator), "*string")}
+	case NullOperator:
+		return []FilterField{filterField(FilterFieldName(field.Name, operator), "*bool")}
+	}
+	return []FilterField{filterField(field.Name, "*"+field.Type)}
+}
+
+// validateOperators reports the first operator which does not apply to the field.
+func validateOperators(field Field) error {
+	for _, operator := range field.Operators {
+		switch operator {
+		case EqualOperator:
+		case RangeOperator:
+			if !isNumberType(field.Type) && field.Type != "time.Time" {
+				return fmt.Errorf("range needs a number or a time.Time, not %s", field.Type)
+			}
+		case InOperator:
+			if field.Type == "bool" {
+				return fmt.Errorf("in needs a type with more than two values, not bool")
+			}
+		case ContainsOperator:
+			if field.Type != "string" {
+				return fmt.Errorf("contains needs a string, not %s", field.Type)
+			}
+		case NullOperator:
+			if !field.Nullable {
+				return fmt.Errorf("null needs a nullable field")
+			}
+		default:
+			return fmt.Errorf("unknown filter operator %q", operator)
+		}
+	}
+	return nil
+}
+
+// filterPrompt returns the filterable fields with their operators, e.g. Email, Name (eq, contains).
+func filterPrompt(fields []Field) string {
+	filters := []string{}
+	for _, field := range fields {
+		operators := field.FilterOperators()
+		if len(operators) == 0 {
+			continue
+		}
+		if len(operators) == 1 && operators[0] == EqualOperator {
+			filters = append(filters, field.Name)
+			continue
+		}
+		names := make([]string, len(operators))
+		for i, operator := range operators {
+			names[i] = string(operator)
+		}
+		filters = append(filters, field.Name+" ("+strings.Join(names, ", ")+")")
+	}
+	return strings.Join(filters, ", ")
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateOperators(t *testing.T) {
+	testCases := map[string]struct {
+		field   Field
+		wantErr string
+	}{
+		"no operators":       {field: Field{Name: "Name", Type: "string"}},
+		"eq":                 {field: Field{Name: "Active", Type: "bool", Operators: []Operator{EqualOperator}}},
+		"range of number":    {field: Field{Name: "Price", Type: "float64", Operators: []Operator{RangeOperator}}},
+		"range of time":      {field: Field{Name: "CreatedAt", Type: "time.Time", Operators: []Operator{RangeOperator}}},
+		"range of string":    {field: Field{Name: "Name", Type: "string", Operators: []Operator{RangeOperator}}, wantErr: "range needs a number or a time.Time"},
+		"in":                 {field: Field{Name: "ID", Type: "uuid.UUID", Operators: []Operator{InOperator}}},
+		"in of bool":         {field: Field{Name: "Active", Type: "bool", Operators: []Operator{InOperator}}, wantErr: "in needs a type"},
+		"contains":           {field: Field{Name: "Name", Type: "string", Operators: []Operator{EqualOperator, ContainsOperator}}},
+		"contains of int":    {field: Field{Name: "Age", Type: "int", Operators: []Operator{ContainsOperator}}, wantErr: "contains needs a string"},
+		"null":               {field: Field{Name: "DeletedAt", Type: "time.Time", Nullable: true, Operators: []Operator{NullOperator}}},
+		"null of not null":   {field: Field{Name: "Name", Type: "string", Operators: []Operator{NullOperator}}, wantErr: "null needs a nullable field"},
+		"unknown operator":   {field: Field{Name: "Name", Type: "string", Operators: []Operator{"like"}}, wantErr: `unknown filter operator "like"`},
+		"first invalid only": {field: Field{Name: "Name", Type: "string", Operators: []Operator{ContainsOperator, RangeOperator, "like"}}, wantErr: "range"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := validateOperators(tc.field)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateOperators: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("validateOperators() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEntity_FilterFields(t *testing.T) {
+	testCases := map[string]struct {
+		fields        []Field
+		want          []string
+		wantOperators bool
+	}{
+		"not filterable": {
+			fields: []Field{{Name: "ID", Type: "int64"}, {Name: "Name", Type: "string"}},
+			want:   []string{},
+		},
+		"eq by default": {
+			fields: []Field{{Name: "Email", Type: "string", Filterable: true}},
+			want:   []string{"Email *string email eq"},
+		},
+		"every operator": {
+			fields: []Field{
+				{Name: "ID", Type: "uuid.UUID", Operators: []Operator{InOperator}},
+				{Name: "Name", Type: "string", Operators: []Operator{EqualOperator, ContainsOperator}},
+				{Name: "CreatedAt", Type: "time.Time", Operators: []Operator{RangeOperator}},
+				{Name: "DeletedAt", Type: "time.Time", Nullable: true, Operators: []Operator{NullOperator}},
+			},
+			want: []string{
+				"IDs []uuid.UUID ids in",
+				"Name *string name eq",
+				"NameContains *string name_contains contains",
+				"StartCreatedAt *time.Time start_created_at range",
+				"EndCreatedAt *time.Time end_created_at range",
+				"DeletedAtIsNull *bool deleted_at_is_null null",
+			},
+			wantOperators: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := Entity{Name: "user", Fields: tc.fields}
+			got := []string{}
+			for _, field := range e.FilterFields() {
+				got = append(got, strings.Join([]string{field.Name, field.Type, field.Param, string(field.Operator)}, " "))
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("FilterFields() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+			if got := e.HasFilterOperators(); got != tc.wantOperators {
+				t.Errorf("HasFilterOperators() = %t, want %t", got, tc.wantOperators)
+			}
+		})
+	}
+}
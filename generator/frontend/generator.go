@@ -0,0 +1,122 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/go-flexi/codegenerator/generator"
+	"github.com/go-flexi/codegenerator/generator/prompt"
+	"github.com/go-flexi/codegenerator/openai"
+)
+
+// Dir is the directory of the generated clients of the entities.
+const Dir = "web/src/api"
+
+// Generator generates the TypeScript client of the backend using OpenAI API.
+type Generator struct {
+	api     *openai.API
+	prompts *prompt.Library
+	module  generator.Module
+
+	messages generator.Messages
+	entity   string
+	ir       generator.Entity
+	files    generator.Files
+}
+
+// ErrNoEntity is returned when the client is generated before the model is added.
+var ErrNoEntity = errors.New("entity is unknown, add the model first")
+
+// NewGenerator creates a new Generator with the frontend prompt of the library.
+func NewGenerator(api *openai.API, prompts *prompt.Library, module generator.Module) (*Generator, error) {
+	g := Generator{
+		api:     api,
+		prompts: prompts,
+		module:  module,
+		files:   generator.Files{},
+	}
+
+	system, err := g.prompt("frontend")
+	if err != nil {
+		return nil, fmt.Errorf("prompt: %w", err)
+	}
+	g.messages = generator.NewMessages(system)
+
+	return &g, nil
+}
+
+// AddFiles adds the model, filter and order files of the entity package to the
+// conversation. The entity is taken from the package of model.go.
+func (g *Generator) AddFiles(files ...generator.File) {
+	entity := g.files.AddFiles(&g.messages, "existing code of the backend", files...)
+	if g.entity == "" {
+		g.entity = entity
+	}
+}
+
+// SetEntity sets the entity of the package, its pagination selects the query of the
+// client.
+func (g *Generator) SetEntity(entity generator.Entity) {
+	g.ir = entity
+}
+
+// TypesCall generates the TypeScript types of the entity.
+func (g *Generator) TypesCall() (string, error) {
+	return g.fileCall("tstypes", "types.ts")
+}
+
+// ClientCall generates the typed fetch client of the entity endpoints.
+func (g *Generator) ClientCall() (string, error) {
+	return g.fileCall("tsclient", "client.ts")
+}
+
+// HooksCall generates the React Query hooks of the client.
+func (g *Generator) HooksCall() (string, error) {
+	return g.fileCall("tshooks", "hooks.ts")
+}
+
+// FilePath returns the path of the generated file of the entity.
+func (g *Generator) FilePath(name string) string {
+	return path.Join(Dir, g.entity, name)
+}
+
+// File returns the last generated code of the file.
+func (g *Generator) File(filePath string) (string, bool) {
+	return g.files.File(filePath)
+}
+
+// fileCall generates the file of the entity with the prompt.
+func (g *Generator) fileCall(name, fileName string) (string, error) {
+	if g.entity == "" {
+		return "", ErrNoEntity
+	}
+
+	message, err := g.prompt(name)
+	if err != nil {
+		return "", fmt.Errorf("prompt: %w", err)
+	}
+
+	filePath := g.FilePath(fileName)
+	g.messages.AddUserMessage(message + "\n" + "write the code for " + filePath)
+	if err := g.messages.Send(context.Background(), g.api); err != nil {
+		return "", fmt.Errorf("messages.Send: %w", err)
+	}
+
+	code := g.messages.LastAsistantMessage()
+	g.files[filePath] = code
+	return code, nil
+}
+
+// prompt renders the prompt with the name.
+func (g *Generator) prompt(name string) (string, error) {
+	return g.prompts.Render(name, prompt.Vars{
+		Module:     g.module.Path,
+		Org:        g.module.Org,
+		Project:    g.module.Project,
+		Entity:     g.entity,
+		Layer:      name,
+		Pagination: g.ir.Pagination,
+	})
+}
@@ -0,0 +1,24 @@
+package frontend
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// scaffoldDir holds the shared files the generated clients import.
+const scaffoldDir = "scaffold"
+
+//go:embed scaffold
+var scaffoldFS embed.FS
+
+// Scaffold returns the shared request helper the generated clients import,
+// web/src/api/http.ts, leaving out the files which already exist in the project.
+func Scaffold(w *generator.Writer) ([]generator.File, error) {
+	files, err := generator.ScaffoldFiles(w, scaffoldFS, scaffoldDir)
+	if err != nil {
+		return nil, fmt.Errorf("generator.ScaffoldFiles: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,55 @@
+// baseURL is the url of the backend, empty when the web client is served by the backend.
+export let baseURL = "";
+
+// setBaseURL sets the url of the backend.
+export function setBaseURL(url: string) {
+  baseURL = url.replace(/\/+$/, "");
+}
+
+// ApiError is the error returned by the backend.
+export class ApiError extends Error {
+  status: number;
+  fields?: Record<string, string>;
+
+  constructor(status: number, message: string, fields?: Record<string, string>) {
+    super(message);
+    this.name = "ApiError";
+    this.status = status;
+    this.fields = fields;
+  }
+}
+
+// RequestOptions are the query string, the JSON body and the abort signal of a request.
+export interface RequestOptions {
+  query?: Record<string, string | number | boolean | undefined>;
+  body?: unknown;
+  signal?: AbortSignal;
+}
+
+// request sends the request to the backend and decodes the JSON response.
+export async function request<T>(method: string, path: string, options: RequestOptions = {}): Promise<T> {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(options.query ?? {})) {
+    if (value !== undefined) {
+      params.set(key, String(value));
+    }
+  }
+  const query = params.toString();
+
+  const response = await fetch(baseURL + path + (query ? "?" + query : ""), {
+    method,
+    headers: options.body === undefined ? undefined : { "Content-Type": "application/json" },
+    body: options.body === undefined ? undefined : JSON.stringify(options.body),
+    signal: options.signal,
+  });
+
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: response.statusText }));
+    throw new ApiError(response.status, error.error, error.fields);
+  }
+
+  if (response.status === 204) {
+    return undefined as T;
+  }
+  return (await response.json()) as T;
+}
@@ -0,0 +1,28 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ErrNoToolchain is returned when the go toolchain is not installed.
+var ErrNoToolchain = errors.New("go toolchain not found")
+
+// GoTest runs go test for the packages of the project directory and returns its output.
+func GoTest(ctx context.Context, dir string, pkgs ...string) (string, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return "", ErrNoToolchain
+	}
+
+	cmd := exec.CommandContext(ctx, goBin, append([]string{"test"}, pkgs...)...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("go test: %w", err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,452 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RelationKind is the kind of a relation between two entities.
+type RelationKind string
+
+// list of relation kinds
+const (
+	BelongsTo  RelationKind = "belongs_to"
+	HasMany    RelationKind = "has_many"
+	ManyToMany RelationKind = "many_to_many"
+)
+
+// Pagination is the pagination of the queries of an entity.
+type Pagination string
+
+// list of paginations
+const (
+	// OffsetPagination queries the pages by number with LIMIT and OFFSET.
+	OffsetPagination Pagination = "offset"
+	// CursorPagination queries the pages after or before an opaque cursor of the order by
+	// column and the id of a row, using the (column, id) row values of the keyset.
+	CursorPagination Pagination = "cursor"
+)
+
+// Entity is the intermediate representation of an entity. The inputs, a go model, a SQL
+// schema, a JSON Schema or a manifest, produce it and the generators consume it.
+type Entity struct {
+	// Name is the package name of the entity, e.g. orderitem.
+	Name string `json:"name" yaml:"name"`
+	// Struct is the name of the entity struct, e.g. OrderItem.
+	Struct string `json:"struct,omitempty" yaml:"struct,omitempty"`
+	// Table is the database table of the entity, e.g. order_items.
+	Table     string     `json:"table,omitempty" yaml:"table,omitempty"`
+	Fields    []Field    `json:"fields" yaml:"fields,omitempty"`
+	Relations []Relation `json:"relations,omitempty" yaml:"relations,omitempty"`
+	// Policy is the authorization policy of the permission checks of the core.
+	Policy *Policy `json:"policy,omitempty" yaml:"policy,omitempty"`
+	// SoftDelete marks the deleted entities with DeletedAt instead of deleting the rows.
+	SoftDelete bool `json:"soft_delete,omitempty" yaml:"soft_delete,omitempty"`
+	// Audit records the subject of the claims which created and last updated the entity in
+	// CreatedBy and UpdatedBy.
+	Audit bool `json:"audit,omitempty" yaml:"audit,omitempty"`
+	// Versioned entities have a Version which Update checks for optimistic locking.
+	Versioned bool `json:"versioned,omitempty" yaml:"versioned,omitempty"`
+	// MultiTenant entities belong to the tenant of the claims of the context in TenantID, the
+	// store scopes every query and update to it.
+	MultiTenant bool `json:"multi_tenant,omitempty" yaml:"multi_tenant,omitempty"`
+	// Events are the domain events of the writes of the entity, persisted in the outbox in
+	// the transaction of the writes.
+	Events bool `json:"events,omitempty" yaml:"events,omitempty"`
+	// Pagination is the pagination of the queries, the offset pagination by default.
+	Pagination Pagination `json:"pagination,omitempty" yaml:"pagination,omitempty"`
+}
+
+// list of the fields of the features of an entity
+var (
+	DeletedAtField = Field{Name: "DeletedAt", Type: "time.Time", Nullable: true}
+	CreatedByField = Field{Name: "CreatedBy", Type: "string", Immutable: true}
+	UpdatedByField = Field{Name: "UpdatedBy", Type: "string"}
+	VersionField   = Field{Name: "Version", Type: "int"}
+	TenantIDField  = Field{Name: "TenantID", Type: "string", Immutable: true}
+)
+
+// Field is a field of an entity.
+type Field struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is the go type of the field without the pointer, e.g. uuid.UUID.
+	Type       string `json:"type" yaml:"type"`
+	Column     string `json:"column,omitempty" yaml:"column,omitempty"`
+	Nullable   bool   `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	PrimaryKey bool   `json:"primary_key,omitempty" yaml:"primary_key,omitempty"`
+	Filterable bool   `json:"filterable,omitempty" yaml:"filterable,omitempty"`
+	// Operators are the filter operators of the field, a field with operators is filterable.
+	Operators []Operator `json:"operators,omitempty" yaml:"operators,omitempty"`
+	Sortable  bool       `json:"sortable,omitempty" yaml:"sortable,omitempty"`
+	// Updatable fields are part of the update of the entity.
+	Updatable bool `json:"updatable,omitempty" yaml:"updatable,omitempty"`
+	// Immutable fields are set when the entity is created and never change, e.g. CreatedAt.
+	Immutable bool `json:"immutable,omitempty" yaml:"immutable,omitempty"`
+	// Sensitive fields, e.g. PasswordHash, are left out of the domain events, the fields
+	// named after a password, a secret or a token are sensitive by default.
+	Sensitive bool `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+	// Validate are the validation rules of the field.
+	Validate *Rules `json:"validate,omitempty" yaml:"validate,omitempty"`
+}
+
+// list of on delete rules of the foreign keys
+const (
+	Cascade  = "cascade"
+	Restrict = "restrict"
+	SetNull  = "set null"
+)
+
+// Relation is a relation of an entity to another entity.
+type Relation struct {
+	Name   string       `json:"name" yaml:"name"`
+	Kind   RelationKind `json:"kind" yaml:"kind"`
+	Entity string       `json:"entity" yaml:"entity"`
+	// Table is the table of the related entity, the plural of the entity by default.
+	Table string `json:"table,omitempty" yaml:"table,omitempty"`
+	// Field is the foreign key field of a belongs_to relation, e.g. UserID.
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+	// OnDelete is the rule of the foreign key of a belongs_to relation when the related
+	// entity is deleted, cascade, restrict or set null.
+	OnDelete string `json:"on_delete,omitempty" yaml:"on_delete,omitempty"`
+	// JoinTable is the table of a many_to_many relation, e.g. order_tags.
+	JoinTable string `json:"join_table,omitempty" yaml:"join_table,omitempty"`
+}
+
+// NewEntity returns the entity with the default struct and table names of the package name.
+func NewEntity(name string) Entity {
+	e := Entity{Name: name, Fields: []Field{}}
+	e.SetDefaults()
+	return e
+}
+
+// ParseEntities parses the JSON of an entity or of a list of entities.
+func ParseEntities(data []byte) ([]Entity, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		data = append(append([]byte("["), data...), ']')
+	}
+
+	entities := []Entity{}
+	if err := json.Unmarshal(data, &entities); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	for i := range entities {
+		entities[i].SetDefaults()
+		if err := entities[i].Validate(); err != nil {
+			return nil, fmt.Errorf("entities[%d]: %w", i, err)
+		}
+	}
+	return entities, nil
+}
+
+// SetDefaults fills in the struct, table and column names which are not set, the struct of
+// a multi-word name is taken from the words of the table, e.g. OrderItem of orderitem and
+// order_items. The foreign keys of the belongs_to relations are filterable, the policy model
+// is the role model by default, the passwords, secrets and tokens are sensitive. The fields
+// of the features are added, and the features of the fields are turned on, e.g. a DeletedAt
+// field turns on soft delete.
+func (e *Entity) SetDefaults() {
+	if singular := Singular(e.Table); e.Struct == "" && strings.ReplaceAll(singular, "_", "") == e.Name {
+		e.Struct = PascalCase(singular)
+	}
+	if e.Struct == "" && e.Name != "" {
+		e.Struct = PascalCase(e.Name)
+	}
+	if e.Table == "" && e.Struct != "" {
+		e.Table = Plural(SnakeCase(e.Struct))
+	}
+
+	_, deletedAt := e.Field(DeletedAtField.Name)
+	_, createdBy := e.Field(CreatedByField.Name)
+	_, updatedBy := e.Field(UpdatedByField.Name)
+	_, version := e.Field(VersionField.Name)
+	_, tenantID := e.Field(TenantIDField.Name)
+	e.SoftDelete = e.SoftDelete || deletedAt
+	e.Audit = e.Audit || (createdBy && updatedBy)
+	e.Versioned = e.Versioned || version
+	e.MultiTenant = e.MultiTenant || tenantID
+	if e.SoftDelete {
+		e.addField(DeletedAtField)
+	}
+	if e.Audit {
+		e.addField(CreatedByField)
+		e.addField(UpdatedByField)
+	}
+	if e.Versioned {
+		e.addField(VersionField)
+	}
+	if e.MultiTenant {
+		e.addField(TenantIDField)
+	}
+	for i := range e.Fields {
+		// the fields of the features are set by the core and the store, not by the updates
+		for _, feature := range []Field{DeletedAtField, CreatedByField, UpdatedByField, VersionField, TenantIDField} {
+			if e.Fields[i].Name == feature.Name {
+				e.Fields[i].Updatable = false
+				e.Fields[i].Immutable = e.Fields[i].Immutable || feature.Immutable
+			}
+		}
+		// the tenant is scoped from the context, the clients never filter by it
+		if e.MultiTenant && e.Fields[i].Name == TenantIDField.Name {
+			e.Fields[i].Filterable = false
+			e.Fields[i].Operators = nil
+		}
+	}
+	for i := range e.Fields {
+		if e.Fields[i].Column == "" {
+			e.Fields[i].Column = SnakeCase(e.Fields[i].Name)
+		}
+		if e.Fields[i].Name == "ID" && !e.hasPrimaryKey() {
+			e.Fields[i].PrimaryKey = true
+		}
+		if len(e.Fields[i].Operators) > 0 {
+			e.Fields[i].Filterable = true
+		}
+		for _, word := range strings.Split(SnakeCase(e.Fields[i].Name), "_") {
+			if word == "password" || word == "secret" || word == "token" {
+				e.Fields[i].Sensitive = true
+			}
+		}
+	}
+
+	for i := range e.Relations {
+		relation := &e.Relations[i]
+		if relation.Table == "" {
+			relation.Table = Plural(SnakeCase(relation.Entity))
+		}
+		if relation.Kind == ManyToMany && relation.JoinTable == "" {
+			relation.JoinTable = SnakeCase(e.Struct) + "_" + relation.Table
+		}
+		if relation.Kind != BelongsTo {
+			continue
+		}
+		for j := range e.Fields {
+			if e.Fields[j].Name == relation.Field {
+				e.Fields[j].Filterable = true
+			}
+		}
+	}
+
+	if e.Policy != nil && e.Policy.Model == "" {
+		e.Policy.Model = RolePolicy
+	}
+}
+
+// Validate reports the first invalid name, type or relation of the entity.
+func (e Entity) Validate() error {
+	if e.Name == "" || strings.ToLower(e.Name) != e.Name || strings.ContainsAny(e.Name, " /.-_") {
+		return fmt.Errorf("name %q must be a lower case package name", e.Name)
+	}
+
+	names := map[string]bool{}
+	for _, field := range e.Fields {
+		if field.Name == "" || field.Type == "" {
+			return fmt.Errorf("%s: fields need a name and a type", e.Name)
+		}
+		if strings.HasPrefix(field.Type, "*") {
+			return fmt.Errorf("%s.%s: type %s is a pointer, set nullable instead", e.Name, field.Name, field.Type)
+		}
+		if names[field.Name] {
+			return fmt.Errorf("%s: duplicate field %s", e.Name, field.Name)
+		}
+		names[field.Name] = true
+
+		if field.Validate != nil {
+			if err := field.Validate.Validate(field.Type); err != nil {
+				return fmt.Errorf("%s.%s: %w", e.Name, field.Name, err)
+			}
+		}
+		if err := validateOperators(field); err != nil {
+			return fmt.Errorf("%s.%s: %w", e.Name, field.Name, err)
+		}
+	}
+
+	if len(e.Fields) > 0 && !e.hasPrimaryKey() {
+		return fmt.Errorf("%s: no field is the primary key, add an ID field or set primary_key", e.Name)
+	}
+
+	for _, relation := range e.Relations {
+		switch relation.Kind {
+		case BelongsTo, HasMany, ManyToMany:
+		default:
+			return fmt.Errorf("%s.%s: unknown relation kind %q", e.Name, relation.Name, relation.Kind)
+		}
+		if relation.Entity == "" {
+			return fmt.Errorf("%s.%s: relation needs the entity", e.Name, relation.Name)
+		}
+		if relation.Kind == BelongsTo && !names[relation.Field] {
+			return fmt.Errorf("%s.%s: foreign key %q is not a field", e.Name, relation.Name, relation.Field)
+		}
+		switch relation.OnDelete {
+		case "", Cascade, Restrict, SetNull:
+		default:
+			return fmt.Errorf("%s.%s: unknown on delete rule %q", e.Name, relation.Name, relation.OnDelete)
+		}
+		if relation.OnDelete == SetNull {
+			if field, _ := e.Field(relation.Field); !field.Nullable {
+				return fmt.Errorf("%s.%s: set null needs the nullable field %s", e.Name, relation.Name, relation.Field)
+			}
+		}
+	}
+
+	if err := e.validateFeatures(); err != nil {
+		return fmt.Errorf("%s: %w", e.Name, err)
+	}
+
+	switch e.Pagination {
+	case "", OffsetPagination, CursorPagination:
+	default:
+		return fmt.Errorf("%s: unknown pagination %q", e.Name, e.Pagination)
+	}
+
+	if e.Policy != nil {
+		if err := e.Policy.validate(e); err != nil {
+			return fmt.Errorf("%s.policy: %w", e.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Field returns the field by name.
+func (e Entity) Field(name string) (Field, bool) {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Filters returns the filterable fields.
+func (e Entity) Filters() []Field {
+	fields := []Field{}
+	for _, f := range e.Fields {
+		if f.Filterable {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// OrderBy returns the columns of the sortable fields.
+func (e Entity) OrderBy() []string {
+	columns := []string{}
+	for _, f := range e.Fields {
+		if f.Sortable {
+			columns = append(columns, f.Column)
+		}
+	}
+	return columns
+}
+
+// GoType returns the go type of the field, a pointer when it is nullable.
+func (f Field) GoType() string {
+	if f.Nullable {
+		return "*" + f.Type
+	}
+	return f.Type
+}
+
+// Prompt returns the model, filter, order, feature, validation and relation information of
+// the entity as the first message of a conversation.
+func (e Entity) Prompt() string {
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "package %s\n\ntype %s struct {\n", e.Name, e.Struct)
+	for _, field := range e.Fields {
+		fmt.Fprintf(&buf, "\t%s %s\n", field.Name, field.GoType())
+	}
+	buf.WriteString("}\n")
+
+	updatable := []string{}
+	for _, field := range e.Fields {
+		if field.Updatable {
+			updatable = append(updatable, field.Name)
+		}
+	}
+
+	if filters := filterPrompt(e.Fields); filters != "" {
+		buf.WriteString("\nfilter by " + filters + "\n")
+	}
+	if orderBy := e.OrderBy(); len(orderBy) > 0 {
+		buf.WriteString("order by " + strings.Join(orderBy, ", ") + "\n")
+	}
+	if len(updatable) > 0 {
+		buf.WriteString("update " + strings.Join(updatable, ", ") + "\n")
+	}
+	if e.SoftDelete {
+		buf.WriteString("soft delete with DeletedAt\n")
+	}
+	if e.Audit {
+		buf.WriteString("audit with CreatedBy and UpdatedBy\n")
+	}
+	if e.Versioned {
+		buf.WriteString("optimistic locking with Version, the update has the Version the client read\n")
+	}
+	if e.MultiTenant {
+		buf.WriteString("multi-tenant with TenantID, scoped to the tenant of the context\n")
+	}
+	if e.Events {
+		fmt.Fprintf(&buf, "domain events %[1]sCreated, %[1]sUpdated and %[1]sDeleted in the outbox\n", e.Struct)
+	}
+	if e.Pagination == CursorPagination {
+		buf.WriteString("cursor pagination\n")
+	}
+	for _, field := range e.Fields {
+		if field.Validate != nil {
+			fmt.Fprintf(&buf, "validate %s: %s\n", field.Name, field.Validate)
+		}
+	}
+	for _, relation := range e.Relations {
+		fmt.Fprintf(&buf, "%s %s %s", relation.Name, strings.ReplaceAll(string(relation.Kind), "_", " "), relation.Entity)
+		switch {
+		case relation.Field != "":
+			buf.WriteString(" through " + relation.Field)
+		case relation.JoinTable != "":
+			buf.WriteString(" through the " + relation.JoinTable + " table")
+		}
+		if relation.OnDelete != "" {
+			buf.WriteString(", on delete " + relation.OnDelete)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// validateFeatures reports the first field of a feature with another type than the field
+// of the feature. The fields of an entity without fields are read from its package.
+func (e Entity) validateFeatures() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	if field, _ := e.Field(DeletedAtField.Name); e.SoftDelete && (field.Type != "time.Time" || !field.Nullable) {
+		return fmt.Errorf("soft delete needs the nullable time.Time field DeletedAt")
+	}
+	if field, _ := e.Field(VersionField.Name); e.Versioned && field.Type != "int" && field.Type != "int64" {
+		return fmt.Errorf("optimistic locking needs the int field Version")
+	}
+	if field, _ := e.Field(TenantIDField.Name); e.MultiTenant && (field.Nullable || (field.Type != "string" && field.Type != "uuid.UUID")) {
+		return fmt.Errorf("multi-tenancy needs the string or uuid.UUID field TenantID")
+	}
+	return nil
+}
+
+// addField appends the field when the entity has fields but none with its name, the fields
+// of an entity without fields are read from its package.
+func (e *Entity) addField(field Field) {
+	if _, ok := e.Field(field.Name); !ok && len(e.Fields) > 0 {
+		e.Fields = append(e.Fields, field)
+	}
+}
+
+func (e Entity) hasPrimaryKey() bool {
+	for _, f := range e.Fields {
+		if f.PrimaryKey {
+			return true
+		}
+	}
+	return false
+}
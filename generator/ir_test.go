@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEntity_SetDefaults(t *testing.T) {
+	testCases := map[string]struct {
+		entity     Entity
+		wantStruct string
+		wantTable  string
+		check      func(t *testing.T, e Entity)
+	}{
+		"single word": {
+			entity:     Entity{Name: "user"},
+			wantStruct: "User",
+			wantTable:  "users",
+		},
+		"multi-word table": {
+			entity:     Entity{Name: "orderitem", Table: "order_items"},
+			wantStruct: "OrderItem",
+			wantTable:  "order_items",
+		},
+		"table of another name": {
+			entity:     Entity{Name: "user", Table: "app_accounts"},
+			wantStruct: "User",
+			wantTable:  "app_accounts",
+		},
+		"explicit struct": {
+			entity:     Entity{Name: "orderitem", Struct: "OrderItem"},
+			wantStruct: "OrderItem",
+			wantTable:  "order_items",
+		},
+		"columns and primary key": {
+			entity:     Entity{Name: "user", Fields: []Field{{Name: "ID", Type: "uuid.UUID"}, {Name: "PasswordHash", Type: "[]byte"}}},
+			wantStruct: "User",
+			wantTable:  "users",
+			check: func(t *testing.T, e Entity) {
+				if !e.Fields[0].PrimaryKey || e.Fields[1].Column != "password_hash" || e.Fields[0].Sensitive || !e.Fields[1].Sensitive {
+					t.Errorf("fields = %+v", e.Fields)
+				}
+			},
+		},
+		"features add their fields": {
+			entity:     Entity{Name: "tag", SoftDelete: true, Versioned: true, Fields: []Field{{Name: "ID", Type: "int64"}}},
+			wantStruct: "Tag",
+			wantTable:  "tags",
+			check: func(t *testing.T, e Entity) {
+				if _, ok := e.Field(DeletedAtField.Name); !ok {
+					t.Errorf("no DeletedAt field")
+				}
+				if version, _ := e.Field(VersionField.Name); version.Updatable || version.Column != "version" {
+					t.Errorf("Version = %+v", version)
+				}
+			},
+		},
+		"fields turn on the features": {
+			entity:     Entity{Name: "tag", Fields: []Field{{Name: "ID", Type: "int64"}, {Name: "TenantID", Type: "string", Filterable: true}}},
+			wantStruct: "Tag",
+			wantTable:  "tags",
+			check: func(t *testing.T, e Entity) {
+				if tenant, _ := e.Field(TenantIDField.Name); !e.MultiTenant || tenant.Filterable || !tenant.Immutable {
+					t.Errorf("MultiTenant = %v, TenantID = %+v", e.MultiTenant, tenant)
+				}
+			},
+		},
+		"relations": {
+			entity: Entity{
+				Name:   "order",
+				Fields: []Field{{Name: "ID", Type: "uuid.UUID"}, {Name: "UserID", Type: "uuid.UUID"}},
+				Relations: []Relation{
+					{Name: "User", Kind: BelongsTo, Entity: "user", Field: "UserID"},
+					{Name: "Tags", Kind: ManyToMany, Entity: "tag"},
+					{Name: "Items", Kind: HasMany, Entity: "OrderItem"},
+				},
+			},
+			wantStruct: "Order",
+			wantTable:  "orders",
+			check: func(t *testing.T, e Entity) {
+				if userID, _ := e.Field("UserID"); !userID.Filterable {
+					t.Errorf("foreign key is not filterable")
+				}
+				if e.Relations[0].Table != "users" || e.Relations[1].JoinTable != "order_tags" || e.Relations[2].Table != "order_items" {
+					t.Errorf("relations = %+v", e.Relations)
+				}
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := tc.entity
+			e.SetDefaults()
+			if e.Struct != tc.wantStruct || e.Table != tc.wantTable {
+				t.Errorf("SetDefaults() = %s %s, want %s %s", e.Struct, e.Table, tc.wantStruct, tc.wantTable)
+			}
+			if tc.check != nil {
+				tc.check(t, e)
+			}
+		})
+	}
+}
+
+func TestEntity_Validate(t *testing.T) {
+	id := Field{Name: "ID", Type: "uuid.UUID"}
+
+	testCases := map[string]struct {
+		entity  Entity
+		wantErr string
+	}{
+		"valid": {
+			entity: Entity{Name: "user", Fields: []Field{id, {Name: "Name", Type: "string"}}},
+		},
+		"no fields": {
+			entity: Entity{Name: "user"},
+		},
+		"upper case name": {
+			entity:  Entity{Name: "User"},
+			wantErr: "lower case",
+		},
+		"pointer type": {
+			entity:  Entity{Name: "user", Fields: []Field{id, {Name: "Name", Type: "*string"}}},
+			wantErr: "nullable",
+		},
+		"duplicate field": {
+			entity:  Entity{Name: "user", Fields: []Field{id, id}},
+			wantErr: "duplicate",
+		},
+		"no primary key": {
+			entity:  Entity{Name: "log", Fields: []Field{{Name: "Line", Type: "string"}}},
+			wantErr: "primary key",
+		},
+		"explicit primary key": {
+			entity: Entity{Name: "country", Fields: []Field{{Name: "Code", Type: "string", PrimaryKey: true}}},
+		},
+		"foreign key is not a field": {
+			entity:  Entity{Name: "order", Fields: []Field{id}, Relations: []Relation{{Name: "User", Kind: BelongsTo, Entity: "user", Field: "UserID"}}},
+			wantErr: "foreign key",
+		},
+		"set null needs a nullable field": {
+			entity: Entity{
+				Name:      "order",
+				Fields:    []Field{id, {Name: "UserID", Type: "uuid.UUID"}},
+				Relations: []Relation{{Name: "User", Kind: BelongsTo, Entity: "user", Field: "UserID", OnDelete: SetNull}},
+			},
+			wantErr: "set null",
+		},
+		"unknown pagination": {
+			entity:  Entity{Name: "user", Fields: []Field{id}, Pagination: "keyset"},
+			wantErr: "pagination",
+		},
+		"invalid operator": {
+			entity:  Entity{Name: "user", Fields: []Field{id, {Name: "Enabled", Type: "bool", Operators: []Operator{RangeOperator}}}},
+			wantErr: "range",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := tc.entity
+			e.SetDefaults()
+			err := e.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// list of keep markers
+const (
+	KeepBegin = "// codegen:keep begin"
+	KeepEnd   = "// codegen:keep end"
+)
+
+// KeepRegion is a hand-written block of code which survives regeneration.
+type KeepRegion struct {
+	// Name is the optional text after the begin marker.
+	Name string
+	// Anchor is the nearest non-blank line before the begin marker.
+	Anchor  string
+	Content string
+
+	indent string
+}
+
+// Key returns the name of the region, or its anchor when it is unnamed.
+func (r KeepRegion) Key() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Anchor
+}
+
+func (r KeepRegion) lines() []string {
+	lines := []string{strings.TrimRight(r.indent+KeepBegin+" "+r.Name, " ")}
+	if r.Content != "" {
+		lines = append(lines, strings.Split(r.Content, "\n")...)
+	}
+	return append(lines, r.indent+KeepEnd)
+}
+
+// Conflict reports a keep region which could not be restored.
+type Conflict struct {
+	Region KeepRegion
+	Reason string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("keep region %q: %s", c.Region.Key(), c.Reason)
+}
+
+// ExtractKeepRegions returns the keep regions found in src.
+func ExtractKeepRegions(src string) ([]KeepRegion, error) {
+	lines := strings.Split(src, "\n")
+	regions := []KeepRegion{}
+	keys := map[string]bool{}
+
+	for i := 0; i < len(lines); i++ {
+		name, ok := keepBeginName(lines[i])
+		if !ok {
+			if isKeepEnd(lines[i]) {
+				return nil, fmt.Errorf("line %d: %q without %q", i+1, KeepEnd, KeepBegin)
+			}
+			continue
+		}
+
+		end, err := keepEndLine(lines, i)
+		if err != nil {
+			return nil, err
+		}
+
+		region := KeepRegion{
+			Name:    name,
+			Anchor:  anchorBefore(lines, i),
+			Content: strings.Join(lines[i+1:end], "\n"),
+			indent:  indentOf(lines[i]),
+		}
+		if keys[region.Key()] {
+			return nil, fmt.Errorf("line %d: duplicate keep region %q, name the regions to tell them apart", i+1, region.Key())
+		}
+		keys[region.Key()] = true
+
+		regions = append(regions, region)
+		i = end
+	}
+
+	return regions, nil
+}
+
+// RestoreKeepRegions re-inserts regions into generated code. A region replaces the
+// content of its markers when the generated code kept them, otherwise it is inserted
+// after its anchor line. Regions which can be placed neither way are reported as conflicts.
+func RestoreKeepRegions(generated string, regions []KeepRegion) (string, []Conflict) {
+	lines := strings.Split(generated, "\n")
+	conflicts := []Conflict{}
+
+	for _, region := range regions {
+		restored, err := restoreKeepRegion(lines, region)
+		if err != nil {
+			conflicts = append(conflicts, Conflict{Region: region, Reason: err.Error()})
+			continue
+		}
+		lines = restored
+	}
+
+	return strings.Join(lines, "\n"), conflicts
+}
+
+// KeepPrompt returns the instruction which asks the model to keep regions verbatim.
+func KeepPrompt(regions []KeepRegion) string {
+	buf := bytes.Buffer{}
+	buf.WriteString("The following hand-written regions must be kept verbatim, ")
+	buf.WriteString("including their marker lines, at the same place in the code:\n")
+	for _, region := range regions {
+		buf.WriteString("\n")
+		if region.Anchor != "" {
+			buf.WriteString("after the line `" + strings.TrimSpace(region.Anchor) + "`\n")
+		}
+		buf.WriteString(strings.Join(region.lines(), "\n"))
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// ConflictReport returns a human readable report of conflicts including the content of
+// the regions, so that no hand-written code is lost.
+func ConflictReport(conflicts []Conflict) string {
+	buf := bytes.Buffer{}
+	for _, conflict := range conflicts {
+		buf.WriteString(conflict.String())
+		buf.WriteString("\n")
+		buf.WriteString(strings.Join(conflict.Region.lines(), "\n"))
+		buf.WriteString("\n\n")
+	}
+	return buf.String()
+}
+
+func restoreKeepRegion(lines []string, region KeepRegion) ([]string, error) {
+	for i := range lines {
+		name, ok := keepBeginName(lines[i])
+		if !ok || name != region.Name {
+			continue
+		}
+		if region.Name == "" && strings.TrimSpace(anchorBefore(lines, i)) != strings.TrimSpace(region.Anchor) {
+			continue
+		}
+
+		end, err := keepEndLine(lines, i)
+		if err != nil {
+			return nil, fmt.Errorf("generated code: %w", err)
+		}
+
+		restored := append([]string{}, lines[:i+1]...)
+		if region.Content != "" {
+			restored = append(restored, strings.Split(region.Content, "\n")...)
+		}
+		return append(restored, lines[end:]...), nil
+	}
+
+	anchor := strings.TrimSpace(region.Anchor)
+	if anchor == "" {
+		return nil, fmt.Errorf("markers were removed and the region has no anchor")
+	}
+
+	at := -1
+	for i := range lines {
+		if strings.TrimSpace(lines[i]) != anchor {
+			continue
+		}
+		if at != -1 {
+			return nil, fmt.Errorf("anchor %q is ambiguous", anchor)
+		}
+		at = i
+	}
+	if at == -1 {
+		return nil, fmt.Errorf("anchor %q disappeared", anchor)
+	}
+
+	restored := append([]string{}, lines[:at+1]...)
+	restored = append(restored, region.lines()...)
+	return append(restored, lines[at+1:]...), nil
+}
+
+func keepBeginName(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, KeepBegin) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, KeepBegin)), true
+}
+
+func isKeepEnd(line string) bool {
+	return strings.TrimSpace(line) == KeepEnd
+}
+
+func keepEndLine(lines []string, begin int) (int, error) {
+	for i := begin + 1; i < len(lines); i++ {
+		if _, ok := keepBeginName(lines[i]); ok {
+			return 0, fmt.Errorf("line %d: nested %q", i+1, KeepBegin)
+		}
+		if isKeepEnd(lines[i]) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("line %d: %q without %q", begin+1, KeepBegin, KeepEnd)
+}
+
+func anchorBefore(lines []string, i int) string {
+	for i--; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+func indentOf(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractKeepRegions(t *testing.T) {
+	testCases := map[string]struct {
+		src     string
+		want    []KeepRegion
+		wantErr string
+	}{
+		"no region": {
+			src:  "package user\n\nfunc f() {}\n",
+			want: []KeepRegion{},
+		},
+		"named region": {
+			src: "func f() {\n\t// codegen:keep begin audit\n\tlog.Println()\n\t// codegen:keep end\n}\n",
+			want: []KeepRegion{
+				{Name: "audit", Anchor: "func f() {", Content: "\tlog.Println()", indent: "\t"},
+			},
+		},
+		"unnamed region after blank lines": {
+			src: "type User struct {}\n\n// codegen:keep begin\nfunc (u User) String() string { return \"\" }\n// codegen:keep end\n",
+			want: []KeepRegion{
+				{Anchor: "type User struct {}", Content: "func (u User) String() string { return \"\" }"},
+			},
+		},
+		"empty region": {
+			src: "a\n// codegen:keep begin x\n// codegen:keep end\n",
+			want: []KeepRegion{
+				{Name: "x", Anchor: "a"},
+			},
+		},
+		"end without begin": {
+			src:     "a\n// codegen:keep end\n",
+			wantErr: "line 2",
+		},
+		"begin without end": {
+			src:     "// codegen:keep begin\na\n",
+			wantErr: "without",
+		},
+		"nested regions": {
+			src:     "// codegen:keep begin a\n// codegen:keep begin b\n// codegen:keep end\n",
+			wantErr: "nested",
+		},
+		"duplicate names": {
+			src:     "// codegen:keep begin a\n// codegen:keep end\n// codegen:keep begin a\n// codegen:keep end\n",
+			wantErr: "duplicate",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ExtractKeepRegions(tc.src)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ExtractKeepRegions() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractKeepRegions: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("ExtractKeepRegions() = %+v, want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("region %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreKeepRegions(t *testing.T) {
+	existing := "func f() {\n\t// codegen:keep begin audit\n\tlog.Println()\n\t// codegen:keep end\n\treturn\n}\n"
+
+	testCases := map[string]struct {
+		generated     string
+		want          string
+		wantConflicts int
+	}{
+		"markers kept": {
+			generated: "func f() {\n\t// codegen:keep begin audit\n\t// codegen:keep end\n\treturn\n}\n",
+			want:      existing,
+		},
+		"markers removed": {
+			generated: "func f() {\n\treturn\n}\n",
+			want:      existing,
+		},
+		"anchor disappeared": {
+			generated:     "func g() {\n\treturn\n}\n",
+			want:          "func g() {\n\treturn\n}\n",
+			wantConflicts: 1,
+		},
+		"anchor ambiguous": {
+			generated:     "func f() {\n}\nfunc f() {\n}\n",
+			want:          "func f() {\n}\nfunc f() {\n}\n",
+			wantConflicts: 1,
+		},
+	}
+
+	regions, err := ExtractKeepRegions(existing)
+	if err != nil {
+		t.Fatalf("ExtractKeepRegions: %v", err)
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, conflicts := RestoreKeepRegions(tc.generated, regions)
+			if got != tc.want {
+				t.Errorf("RestoreKeepRegions() =\n%s\nwant\n%s", got, tc.want)
+			}
+			if len(conflicts) != tc.wantConflicts {
+				t.Errorf("RestoreKeepRegions() conflicts = %v, want %d", conflicts, tc.wantConflicts)
+			}
+			if len(conflicts) > 0 && !strings.Contains(ConflictReport(conflicts), "log.Println()") {
+				t.Errorf("ConflictReport() lost the content of the region")
+			}
+		})
+	}
+}
+
+func TestKeepPrompt(t *testing.T) {
+	regions := []KeepRegion{{Name: "audit", Anchor: "func f() {", Content: "\tlog.Println()", indent: "\t"}}
+
+	got := KeepPrompt(regions)
+	for _, want := range []string{"after the line `func f() {`", "\t// codegen:keep begin audit\n\tlog.Println()\n\t// codegen:keep end"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("KeepPrompt() = %q, want it to contain %q", got, want)
+		}
+	}
+}
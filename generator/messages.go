@@ -1,10 +1,16 @@
 package generator
 
-import "github.com/go-flexi/codegenerator/openai"
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-flexi/codegenerator/openai"
+)
 
 // Messages is a struct to manage messages.
 type Messages struct {
 	messages []openai.Message
+	usage    openai.Usage
 }
 
 // NewMessages creates a new Messages with a system message.
@@ -46,3 +52,23 @@ func (m *Messages) LastAsistantMessage() string {
 	}
 	return ""
 }
+
+// Send sends the messages to the API and adds the answer as an assistant message.
+func (m *Messages) Send(ctx context.Context, api *openai.API) error {
+	response, err := api.Send(ctx, openai.DefaultConfig(), m.messages)
+	if err != nil {
+		return fmt.Errorf("api.Send: %w", err)
+	}
+	m.usage = m.usage.Add(response.Usage)
+
+	if len(response.Choices) > 0 {
+		m.AddAssistantMessage(response.Choices[0].Message.Content)
+	}
+
+	return nil
+}
+
+// Usage returns the tokens used by the messages sent so far.
+func (m *Messages) Usage() openai.Usage {
+	return m.usage
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrNoModule is returned when the project has no go.mod.
+var ErrNoModule = errors.New("go.mod not found, run init with the module path first")
+
+// Module is the go module of the generated project.
+type Module struct {
+	// Path is the module path the generated code imports, e.g. github.com/go-flexi/ecom-backend.
+	Path string
+	// Org and Project are the last two elements of the path, e.g. go-flexi and ecom-backend.
+	Org     string
+	Project string
+}
+
+// NewModule returns the module of the module path.
+func NewModule(modulePath string) (Module, error) {
+	if modulePath == "" || strings.ContainsAny(modulePath, " \t\"'`\\") ||
+		strings.HasPrefix(modulePath, "/") || strings.HasSuffix(modulePath, "/") || path.Clean(modulePath) != modulePath {
+		return Module{}, fmt.Errorf("invalid module path %q", modulePath)
+	}
+
+	m := Module{Path: modulePath, Project: path.Base(modulePath)}
+	if dir := path.Dir(modulePath); dir != "." {
+		m.Org = path.Base(dir)
+	}
+	return m, nil
+}
+
+// ReadModule returns the module of the go.mod of the project.
+func ReadModule(w *Writer) (Module, error) {
+	goMod, err := w.Read("go.mod")
+	if err != nil {
+		return Module{}, fmt.Errorf("Read: %w", err)
+	}
+	if strings.TrimSpace(goMod) == "" {
+		return Module{}, ErrNoModule
+	}
+
+	for _, line := range strings.Split(goMod, "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		modulePath := fields[1]
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+		return NewModule(modulePath)
+	}
+	return Module{}, fmt.Errorf("go.mod has no module directive")
+}
@@ -0,0 +1,57 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadModule(t *testing.T) {
+	testCases := map[string]struct {
+		goMod   string
+		want    Module
+		wantErr error
+	}{
+		"github module": {
+			goMod: "module github.com/go-flexi/ecom-backend\n\ngo 1.22\n",
+			want:  Module{Path: "github.com/go-flexi/ecom-backend", Org: "go-flexi", Project: "ecom-backend"},
+		},
+		"other host with comment": {
+			goMod: "// the shop\nmodule gitlab.example.com/team/shop // api\n",
+			want:  Module{Path: "gitlab.example.com/team/shop", Org: "team", Project: "shop"},
+		},
+		"quoted single element": {
+			goMod: "module \"shop\"\n",
+			want:  Module{Path: "shop", Project: "shop"},
+		},
+		"no go.mod": {
+			wantErr: ErrNoModule,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := NewWriter(t.TempDir())
+			if tc.goMod != "" {
+				if _, err := w.Write("go.mod", tc.goMod); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+
+			got, err := ReadModule(w)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("ReadModule() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ReadModule() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewModule_invalid(t *testing.T) {
+	for _, modulePath := range []string{"", "/abs", "trailing/", "has space/x", "a//b", "a/../b"} {
+		if _, err := NewModule(modulePath); err == nil {
+			t.Errorf("NewModule(%q) succeeded", modulePath)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SnakeCase converts a go identifier to snake case, e.g. PasswordHash to password_hash
+// and UserIDs to user_ids.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	buf := strings.Builder{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			// the s of a plural initialism is not a word, e.g. IDs
+			plural := i+1 < len(runes) && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) && !plural
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				buf.WriteRune('_')
+			}
+		}
+		buf.WriteRune(unicode.ToLower(r))
+	}
+	return buf.String()
+}
+
+// Plural returns the english plural of a lower case word.
+func Plural(word string) string {
+	switch {
+	case strings.HasSuffix(word, "y") && !strings.HasSuffix(word, "ay") &&
+		!strings.HasSuffix(word, "ey") && !strings.HasSuffix(word, "oy"):
+		return strings.TrimSuffix(word, "y") + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	}
+	return word + "s"
+}
+
+// Singular returns the english singular of a plural word, the reverse of Plural. Words which
+// are singular already, e.g. status, address or analysis, are returned unchanged.
+func Singular(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "zes"), strings.HasSuffix(lower, "ches"),
+		strings.HasSuffix(lower, "shes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "uses"):
+		// statuses and buses, but houses and causes
+		if len(lower) > 4 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-5])) {
+			return word[:len(word)-2]
+		}
+		return word[:len(word)-1]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"),
+		strings.HasSuffix(lower, "is"):
+		return word
+	case strings.HasSuffix(lower, "s"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// initialisms are the words written in upper case in go identifiers.
+var initialisms = map[string]bool{
+	"id": true, "uuid": true, "url": true, "uri": true, "api": true, "http": true,
+	"ip": true, "json": true, "sql": true, "html": true, "sku": true,
+}
+
+// CamelCase converts a snake case name to a go identifier, e.g. password_hash to
+// PasswordHash and user_id to UserID.
+func CamelCase(name string) string {
+	buf := strings.Builder{}
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' }) {
+		word = strings.ToLower(word)
+		if initialisms[word] {
+			buf.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		buf.WriteString(string(runes))
+	}
+	return buf.String()
+}
+
+// PascalCase converts a snake case, kebab case or camel case name to a go identifier, e.g.
+// order_item and orderItem to OrderItem.
+func PascalCase(name string) string {
+	return CamelCase(SnakeCase(name))
+}
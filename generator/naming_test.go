@@ -0,0 +1,103 @@
+package generator
+
+import "testing"
+
+func TestSnakeCase(t *testing.T) {
+	testCases := map[string]string{
+		"ID":           "id",
+		"PasswordHash": "password_hash",
+		"UserID":       "user_id",
+		"UserIDs":      "user_ids",
+		"HTTPServer":   "http_server",
+		"Address2":     "address2",
+	}
+
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := SnakeCase(name); got != want {
+				t.Errorf("SnakeCase(%s) = %s, want %s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	testCases := map[string]string{
+		"password_hash": "PasswordHash",
+		"user_id":       "UserID",
+		"api-key":       "APIKey",
+		"order item":    "OrderItem",
+	}
+
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := CamelCase(name); got != want {
+				t.Errorf("CamelCase(%s) = %s, want %s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestPluralSingular(t *testing.T) {
+	testCases := map[string]string{
+		"user":     "users",
+		"category": "categories",
+		"day":      "days",
+		"status":   "statuses",
+		"address":  "addresses",
+		"box":      "boxes",
+		"branch":   "branches",
+		"wish":     "wishes",
+		"bus":      "buses",
+		"house":    "houses",
+		"cause":    "causes",
+	}
+
+	for singular, plural := range testCases {
+		t.Run(singular, func(t *testing.T) {
+			if got := Plural(singular); got != plural {
+				t.Errorf("Plural(%s) = %s, want %s", singular, got, plural)
+			}
+			if got := Singular(plural); got != singular {
+				t.Errorf("Singular(%s) = %s, want %s", plural, got, singular)
+			}
+		})
+	}
+}
+
+func TestSingular_case(t *testing.T) {
+	testCases := map[string]string{
+		"Status":     "Status",
+		"address":    "address",
+		"Statuses":   "Status",
+		"Categories": "Category",
+		"Users":      "User",
+		"analysis":   "analysis",
+	}
+
+	for word, want := range testCases {
+		t.Run(word, func(t *testing.T) {
+			if got := Singular(word); got != want {
+				t.Errorf("Singular(%s) = %s, want %s", word, got, want)
+			}
+		})
+	}
+}
+
+func TestPascalCase(t *testing.T) {
+	testCases := map[string]string{
+		"order_item": "OrderItem",
+		"orderItem":  "OrderItem",
+		"OrderItem":  "OrderItem",
+		"user_id":    "UserID",
+		"orderitem":  "Orderitem",
+	}
+
+	for name, want := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if got := PascalCase(name); got != want {
+				t.Errorf("PascalCase(%s) = %s, want %s", name, got, want)
+			}
+		})
+	}
+}
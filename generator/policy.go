@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PolicyModel is the authorization model of the permission checks of an entity.
+type PolicyModel string
+
+// list of policy models
+const (
+	// RolePolicy allows the actions to the roles of the claims of the context.
+	RolePolicy PolicyModel = "role"
+	// OwnerPolicy allows the actions on the entities the subject of the claims owns.
+	OwnerPolicy PolicyModel = "owner"
+	// AuthorizerPolicy delegates the decisions to an auth.Authorizer, e.g. an OPA or casbin adapter.
+	AuthorizerPolicy PolicyModel = "authorizer"
+)
+
+// list of actions of the permission checks
+const (
+	ActionCreate = "create"
+	ActionGet    = "get"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionQuery  = "query"
+)
+
+// Actions are the actions of the permission checks in the order of the checks.
+var Actions = []string{ActionCreate, ActionGet, ActionUpdate, ActionDelete, ActionQuery}
+
+// Policy is the authorization policy of the permission checks of an entity.
+type Policy struct {
+	Model PolicyModel `json:"model" yaml:"model"`
+	// Roles are the roles allowed per action by the role model. Every authenticated user is
+	// allowed the actions without roles.
+	Roles map[string][]string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Owner is the field of the owner of the entity by the owner model, e.g. UserID, it is
+	// compared to the subject of the claims.
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// Admins are the roles allowed on every entity by the owner model.
+	Admins []string `json:"admins,omitempty" yaml:"admins,omitempty"`
+}
+
+// validate reports the first invalid setting of the policy of the entity.
+func (p Policy) validate(e Entity) error {
+	switch p.Model {
+	case RolePolicy, AuthorizerPolicy:
+	case OwnerPolicy:
+		if p.Owner == "" {
+			return fmt.Errorf("the owner model needs the owner field")
+		}
+		if len(e.Fields) == 0 {
+			// the fields are read from the entity package
+			break
+		}
+		field, ok := e.Field(p.Owner)
+		if !ok {
+			return fmt.Errorf("owner %q is not a field", p.Owner)
+		}
+		if field.Nullable || (field.Type != "string" && field.Type != "uuid.UUID") {
+			return fmt.Errorf("owner %s must be a string or a uuid.UUID, not %s", p.Owner, field.GoType())
+		}
+		// the queries of the owners set the owner of the Filter
+		equal := false
+		for _, operator := range field.FilterOperators() {
+			equal = equal || operator == EqualOperator
+		}
+		if !equal {
+			return fmt.Errorf("owner %s must be filterable with the eq operator", p.Owner)
+		}
+	default:
+		return fmt.Errorf("unknown policy model %q", p.Model)
+	}
+
+	actions := []string{}
+	for action := range p.Roles {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		known := false
+		for _, a := range Actions {
+			known = known || a == action
+		}
+		if !known {
+			return fmt.Errorf("unknown action %q", action)
+		}
+	}
+	if len(p.Roles) > 0 && p.Model != RolePolicy {
+		return fmt.Errorf("roles need the role model, not %s", p.Model)
+	}
+
+	// a role policy without roles allows every authenticated user, which is no policy
+	roles := 0
+	for _, action := range actions {
+		roles += len(p.Roles[action])
+	}
+	if p.Model == RolePolicy && roles == 0 {
+		return fmt.Errorf("the role model needs the roles of an action, e.g. %s: [admin]", ActionDelete)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicy_validate(t *testing.T) {
+	fields := []Field{
+		{Name: "ID", Type: "uuid.UUID"},
+		{Name: "UserID", Type: "uuid.UUID", Filterable: true},
+		{Name: "AuthorID", Type: "string", Operators: []Operator{InOperator}},
+		{Name: "EditorID", Type: "string"},
+		{Name: "ReviewerID", Type: "string", Nullable: true, Filterable: true},
+	}
+
+	testCases := map[string]struct {
+		policy  Policy
+		wantErr string
+	}{
+		"role": {
+			policy: Policy{Model: RolePolicy, Roles: map[string][]string{ActionDelete: {"admin"}}},
+		},
+		"role without roles": {
+			policy:  Policy{Model: RolePolicy},
+			wantErr: "needs the roles",
+		},
+		"role with empty roles": {
+			policy:  Policy{Model: RolePolicy, Roles: map[string][]string{ActionDelete: {}}},
+			wantErr: "needs the roles",
+		},
+		"unknown action": {
+			policy:  Policy{Model: RolePolicy, Roles: map[string][]string{"publish": {"admin"}}},
+			wantErr: "unknown action",
+		},
+		"owner": {
+			policy: Policy{Model: OwnerPolicy, Owner: "UserID", Admins: []string{"admin"}},
+		},
+		"owner without the eq operator": {
+			policy:  Policy{Model: OwnerPolicy, Owner: "AuthorID"},
+			wantErr: "eq operator",
+		},
+		"owner not filterable": {
+			policy:  Policy{Model: OwnerPolicy, Owner: "EditorID"},
+			wantErr: "eq operator",
+		},
+		"nullable owner": {
+			policy:  Policy{Model: OwnerPolicy, Owner: "ReviewerID"},
+			wantErr: "string or a uuid.UUID",
+		},
+		"owner is not a field": {
+			policy:  Policy{Model: OwnerPolicy, Owner: "TeamID"},
+			wantErr: "not a field",
+		},
+		"roles of the owner model": {
+			policy:  Policy{Model: OwnerPolicy, Owner: "UserID", Roles: map[string][]string{ActionGet: {"admin"}}},
+			wantErr: "role model",
+		},
+		"unknown model": {
+			policy:  Policy{Model: "acl"},
+			wantErr: "unknown policy model",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e := Entity{Name: "post", Fields: fields, Policy: &tc.policy}
+			e.SetDefaults()
+			err := e.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
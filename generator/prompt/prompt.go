@@ -0,0 +1,220 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-flexi/codegenerator/generator"
+)
+
+// list of prompt locations
+const (
+	// ProjectDir is the directory of the project prompts which override the embedded ones.
+	ProjectDir  = ".codegenerator/prompts"
+	embeddedDir = "prompts"
+	extension   = ".tmpl"
+)
+
+// list of prompt sources
+const (
+	SourceEmbedded = "embedded"
+	SourceProject  = "project"
+)
+
+//go:embed prompts
+var embedded embed.FS
+
+// ErrNotFound is returned when no prompt has the name.
+var ErrNotFound = errors.New("prompt not found")
+
+// Vars are the variables of the prompt templates.
+type Vars struct {
+	// Module is the module path of the project, e.g. github.com/go-flexi/ecom-backend.
+	Module  string
+	Org     string
+	Project string
+	Entity  string
+	Layer   string
+	// Examples replace the built-in samples of the system prompt when set.
+	Examples []Example
+	// Fields are the fields of the entity with their validation rules.
+	Fields []generator.Field
+	// Filters are the fields of the Filter of the entity with their operators.
+	Filters []generator.FilterField
+	// Relations are the relations of the entity.
+	Relations []generator.Relation
+	// Policy is the authorization policy of the permission checks of the entity.
+	Policy *generator.Policy
+	// SoftDelete, Audit, Versioned, MultiTenant and Events are the features of the entity.
+	SoftDelete  bool
+	Audit       bool
+	Versioned   bool
+	MultiTenant bool
+	Events      bool
+	// Pagination is the pagination of the queries of the entity.
+	Pagination generator.Pagination
+}
+
+// Example is a file of an existing package used as a sample of the project conventions.
+type Example struct {
+	Path string
+	Code string
+}
+
+// Prompt describes a prompt of the library.
+type Prompt struct {
+	Name   string
+	Source string
+}
+
+// funcs are the functions available to the prompts.
+var funcs = template.FuncMap{
+	"singular": generator.Singular,
+}
+
+// Library is the set of prompt templates. The templates of the project directory override
+// the embedded defaults with the same name.
+type Library struct {
+	project fs.FS
+}
+
+// NewLibrary creates a new Library with the prompts of the project directory.
+func NewLibrary(projectDir string) *Library {
+	return &Library{
+		project: os.DirFS(filepath.Join(projectDir, ProjectDir)),
+	}
+}
+
+// List returns the prompts sorted by name.
+func (l *Library) List() ([]Prompt, error) {
+	sources := map[string]string{}
+
+	embeddedNames, err := templateNames(embedded, embeddedDir)
+	if err != nil {
+		return nil, fmt.Errorf("templateNames[%s]: %w", SourceEmbedded, err)
+	}
+	for _, name := range embeddedNames {
+		sources[name] = SourceEmbedded
+	}
+
+	projectNames, err := templateNames(l.project, ".")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("templateNames[%s]: %w", SourceProject, err)
+	}
+	for _, name := range projectNames {
+		sources[name] = SourceProject
+	}
+
+	prompts := make([]Prompt, 0, len(sources))
+	for name, source := range sources {
+		prompts = append(prompts, Prompt{Name: name, Source: source})
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+
+	return prompts, nil
+}
+
+// Text returns the template text of the prompt and its source.
+func (l *Library) Text(name string) (string, string, error) {
+	text, err := fs.ReadFile(l.project, name+extension)
+	if err == nil {
+		return string(text), SourceProject, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", "", fmt.Errorf("fs.ReadFile[%s]: %w", name, err)
+	}
+
+	text, err = fs.ReadFile(embedded, embeddedDir+"/"+name+extension)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("fs.ReadFile[%s]: %w", name, err)
+	}
+	return string(text), SourceEmbedded, nil
+}
+
+// Render renders the prompt with the variables.
+func (l *Library) Render(name string, vars Vars) (string, error) {
+	text, _, err := l.Text(name)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template.Parse[%s]: %w", name, err)
+	}
+
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("template.Execute[%s]: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Validate renders every prompt with sample variables and returns the errors by prompt name.
+func (l *Library) Validate() (map[string]error, error) {
+	prompts, err := l.List()
+	if err != nil {
+		return nil, fmt.Errorf("List: %w", err)
+	}
+
+	vars := Vars{
+		Module:  "github.com/org/project",
+		Org:     "org",
+		Project: "project",
+		Entity:  "entity",
+		Layer:   "layer",
+		Fields: []generator.Field{
+			{Name: "ID", Type: "uuid.UUID", Column: "id", PrimaryKey: true},
+			{Name: "Name", Type: "string", Column: "name", Validate: &generator.Rules{Required: true, Pattern: "^[a-z]+$"},
+				Operators: []generator.Operator{generator.EqualOperator, generator.ContainsOperator}},
+			{Name: "CreatedAt", Type: "time.Time", Column: "created_at", Operators: []generator.Operator{generator.RangeOperator}},
+		},
+		Relations: []generator.Relation{
+			{Name: "User", Kind: generator.BelongsTo, Entity: "user", Table: "users", Field: "UserID", OnDelete: generator.Cascade},
+			{Name: "Items", Kind: generator.HasMany, Entity: "item", Table: "items"},
+			{Name: "Tags", Kind: generator.ManyToMany, Entity: "tag", Table: "tags", JoinTable: "entity_tags"},
+		},
+		Policy:      &generator.Policy{Model: generator.OwnerPolicy, Owner: "UserID", Admins: []string{"admin"}},
+		SoftDelete:  true,
+		Audit:       true,
+		Versioned:   true,
+		MultiTenant: true,
+		Events:      true,
+		Pagination:  generator.CursorPagination,
+	}
+	vars.Filters = generator.Entity{Fields: vars.Fields}.FilterFields()
+	errs := map[string]error{}
+	for _, p := range prompts {
+		vars.Layer = p.Name
+		if _, err := l.Render(p.Name, vars); err != nil {
+			errs[p.Name] = err
+		}
+	}
+	return errs, nil
+}
+
+func templateNames(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), extension) {
+			names = append(names, strings.TrimSuffix(entry.Name(), extension))
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// ScaffoldFiles returns the files of the dir of fsys at their path in the project without
+// the dir, leaving out the files which already exist in the project.
+func ScaffoldFiles(w *Writer, fsys fs.FS, dir string) ([]File, error) {
+	files := []File{}
+	err := fs.WalkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		projectPath := strings.TrimPrefix(path, dir+"/")
+		existing, err := w.Read(projectPath)
+		if err != nil {
+			return fmt.Errorf("Read: %w", err)
+		}
+		if existing != "" {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return fmt.Errorf("ReadFile[%s]: %w", path, err)
+		}
+		files = append(files, File{Path: projectPath, Content: string(content)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fs.WalkDir: %w", err)
+	}
+
+	return files, nil
+}
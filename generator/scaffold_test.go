@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestScaffoldFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"scaffold/pkg/filter/filter.go": {Data: []byte("package filter\n")},
+		"scaffold/pkg/auth/auth.go":     {Data: []byte("package auth\n")},
+	}
+
+	testCases := map[string]struct {
+		existing  map[string]string
+		wantPaths []string
+	}{
+		"new project": {
+			wantPaths: []string{"pkg/auth/auth.go", "pkg/filter/filter.go"},
+		},
+		"existing file is left out": {
+			existing:  map[string]string{"pkg/auth/auth.go": "package auth\n\n// changed\n"},
+			wantPaths: []string{"pkg/filter/filter.go"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := NewWriter(t.TempDir())
+			for filePath, content := range tc.existing {
+				if _, err := w.Write(filePath, content); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+
+			files, err := ScaffoldFiles(w, fsys, "scaffold")
+			if err != nil {
+				t.Fatalf("ScaffoldFiles: %v", err)
+			}
+			if len(files) != len(tc.wantPaths) {
+				t.Fatalf("ScaffoldFiles() = %v, want %v", files, tc.wantPaths)
+			}
+			for i, file := range files {
+				if file.Path != tc.wantPaths[i] {
+					t.Errorf("files[%d] = %s, want %s", i, file.Path, tc.wantPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFiles_AddFiles(t *testing.T) {
+	files := Files{}
+	messages := NewMessages("system")
+
+	entity := files.AddFiles(&messages, "existing code", File{Path: "business/order/filter.go", Content: "package order\n"},
+		File{Path: "business/order/model.go", Content: "package order\n"})
+	if entity != "order" {
+		t.Errorf("AddFiles() = %q, want order", entity)
+	}
+	if code, ok := files.File("business/order/model.go"); !ok || code != "package order\n" {
+		t.Errorf("File() = %q, %v", code, ok)
+	}
+	if got := len(messages.GetMessages()); got != 2 {
+		t.Errorf("messages = %d, want 2", got)
+	}
+}
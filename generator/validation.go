@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rules are the validation rules of a field, checked by the Validate methods of the NewX
+// and UpdateX types of the entity.
+type Rules struct {
+	// Required strings are not empty, required pointers are not nil.
+	Required bool `json:"required,omitempty" yaml:"required,omitempty"`
+	// MinLength and MaxLength are the length limits of a string.
+	MinLength *int `json:"min_length,omitempty" yaml:"min_length,omitempty"`
+	MaxLength *int `json:"max_length,omitempty" yaml:"max_length,omitempty"`
+	// Pattern is a regular expression matching the whole string.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Enum lists the allowed values of a string.
+	Enum  []string `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Email bool     `json:"email,omitempty" yaml:"email,omitempty"`
+	// Min and Max are the range limits of a number.
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// ParseRules parses the rules of a validate struct tag of a field of the go type, e.g.
+// required,min=3,max=64. min and max are the length limits of a string and the range limits
+// of a number. oneof lists the enum values separated by spaces. The regex of a pattern may
+// contain commas, so it is the last rule of the tag.
+func ParseRules(tag, goType string) (*Rules, error) {
+	if tag == "" || tag == "-" {
+		return nil, nil
+	}
+
+	rules := Rules{}
+	for tag != "" {
+		rule := tag
+		if !strings.HasPrefix(tag, "regex=") && !strings.HasPrefix(tag, "pattern=") {
+			rule, tag, _ = strings.Cut(tag, ",")
+		} else {
+			tag = ""
+		}
+
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch key {
+		case "required":
+			rules.Required = true
+		case "email":
+			rules.Email = true
+		case "oneof", "enum":
+			rules.Enum = strings.Fields(value)
+		case "regex", "pattern":
+			rules.Pattern = value
+		case "min", "max", "min_length", "max_length":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			length := int(number)
+			switch {
+			case key == "min_length" || (key == "min" && isStringType(goType)):
+				rules.MinLength = &length
+			case key == "max_length" || (key == "max" && isStringType(goType)):
+				rules.MaxLength = &length
+			case key == "min":
+				rules.Min = &number
+			default:
+				rules.Max = &number
+			}
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown rule %q", key)
+		}
+	}
+	return &rules, nil
+}
+
+// Validate reports the first rule which does not apply to the go type of the field.
+func (r Rules) Validate(goType string) error {
+	if !isStringType(goType) {
+		switch {
+		case r.MinLength != nil || r.MaxLength != nil:
+			return fmt.Errorf("length rules need a string, not %s", goType)
+		case r.Pattern != "":
+			return fmt.Errorf("pattern needs a string, not %s", goType)
+		case len(r.Enum) > 0:
+			return fmt.Errorf("enum needs a string, not %s", goType)
+		case r.Email && goType != "mail.Address":
+			return fmt.Errorf("email needs a string, not %s", goType)
+		}
+	}
+	if (r.Min != nil || r.Max != nil) && !isNumberType(goType) {
+		return fmt.Errorf("range rules need a number, not %s", goType)
+	}
+
+	if r.MinLength != nil && r.MaxLength != nil && *r.MinLength > *r.MaxLength {
+		return fmt.Errorf("min length %d is greater than max length %d", *r.MinLength, *r.MaxLength)
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("min %g is greater than max %g", *r.Min, *r.Max)
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("regexp.Compile: %w", err)
+		}
+	}
+	return nil
+}
+
+// String returns the rules as a list for the prompts, e.g. required, max length 64.
+func (r Rules) String() string {
+	rules := []string{}
+	if r.Required {
+		rules = append(rules, "required")
+	}
+	if r.MinLength != nil {
+		rules = append(rules, fmt.Sprintf("min length %d", *r.MinLength))
+	}
+	if r.MaxLength != nil {
+		rules = append(rules, fmt.Sprintf("max length %d", *r.MaxLength))
+	}
+	if r.Pattern != "" {
+		rules = append(rules, "matches "+r.Pattern)
+	}
+	if len(r.Enum) > 0 {
+		rules = append(rules, "one of "+strings.Join(r.Enum, ", "))
+	}
+	if r.Email {
+		rules = append(rules, "email")
+	}
+	if r.Min != nil {
+		rules = append(rules, fmt.Sprintf("min %g", *r.Min))
+	}
+	if r.Max != nil {
+		rules = append(rules, fmt.Sprintf("max %g", *r.Max))
+	}
+	return strings.Join(rules, ", ")
+}
+
+func isStringType(goType string) bool {
+	return goType == "string" || goType == "[]byte"
+}
+
+func isNumberType(goType string) bool {
+	switch goType {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		return true
+	}
+	return false
+}
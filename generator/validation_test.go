@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	testCases := map[string]struct {
+		tag     string
+		goType  string
+		want    string
+		wantNil bool
+		wantErr string
+	}{
+		"empty":             {tag: "", goType: "string", wantNil: true},
+		"skipped":           {tag: "-", goType: "string", wantNil: true},
+		"string lengths":    {tag: "required,min=3,max=64", goType: "string", want: "required, min length 3, max length 64"},
+		"number range":      {tag: "min=0.5,max=10", goType: "float64", want: "min 0.5, max 10"},
+		"explicit lengths":  {tag: "min_length=1,max_length=8", goType: "int", want: "min length 1, max length 8"},
+		"oneof":             {tag: "oneof=draft published", goType: "string", want: "one of draft, published"},
+		"enum":              {tag: "enum=a b c", goType: "string", want: "one of a, b, c"},
+		"email":             {tag: "required,email", goType: "mail.Address", want: "required, email"},
+		"pattern is last":   {tag: "max=8,regex=^[a-z]{1,3},[0-9]+$", goType: "string", want: "max length 8, matches ^[a-z]{1,3},[0-9]+$"},
+		"pattern alias":     {tag: "pattern=^a,b$", goType: "string", want: "matches ^a,b$"},
+		"spaces and commas": {tag: "required, max=5,", goType: "string", want: "required, max length 5"},
+		"not a number":      {tag: "min=three", goType: "string", wantErr: "min"},
+		"unknown rule":      {tag: "required,uuid", goType: "string", wantErr: `unknown rule "uuid"`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rules, err := ParseRules(tc.tag, tc.goType)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ParseRules() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRules: %v", err)
+			}
+			if tc.wantNil {
+				if rules != nil {
+					t.Errorf("ParseRules() = %+v, want nil", rules)
+				}
+				return
+			}
+			if got := rules.String(); got != tc.want {
+				t.Errorf("ParseRules() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRules_Validate(t *testing.T) {
+	three, five := 3, 5
+	one, ten := 1.0, 10.0
+
+	testCases := map[string]struct {
+		rules   Rules
+		goType  string
+		wantErr string
+	}{
+		"string":           {rules: Rules{Required: true, MinLength: &three, MaxLength: &five, Pattern: "^[a-z]+$"}, goType: "string"},
+		"number":           {rules: Rules{Min: &one, Max: &ten}, goType: "int"},
+		"mail address":     {rules: Rules{Email: true}, goType: "mail.Address"},
+		"length of int":    {rules: Rules{MaxLength: &five}, goType: "int", wantErr: "length rules need a string"},
+		"pattern of bool":  {rules: Rules{Pattern: "^a$"}, goType: "bool", wantErr: "pattern needs a string"},
+		"enum of int":      {rules: Rules{Enum: []string{"a"}}, goType: "int", wantErr: "enum needs a string"},
+		"email of int":     {rules: Rules{Email: true}, goType: "int", wantErr: "email needs a string"},
+		"range of string":  {rules: Rules{Min: &one}, goType: "string", wantErr: "range rules need a number"},
+		"lengths reversed": {rules: Rules{MinLength: &five, MaxLength: &three}, goType: "string", wantErr: "min length 5 is greater"},
+		"range reversed":   {rules: Rules{Min: &ten, Max: &one}, goType: "float64", wantErr: "min 10 is greater"},
+		"invalid pattern":  {rules: Rules{Pattern: "("}, goType: "string", wantErr: "regexp.Compile"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.rules.Validate(tc.goType)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// keepReportSuffix is appended to the file name of the conflict report.
+const keepReportSuffix = ".keep"
+
+// File is a generated file with its path relative to the project directory.
+type File struct {
+	Path    string
+	Content string
+}
+
+// Writer writes generated files into a project directory.
+type Writer struct {
+	root string
+}
+
+// NewWriter creates a new Writer rooted at the project directory.
+func NewWriter(root string) *Writer {
+	return &Writer{
+		root: root,
+	}
+}
+
+// Read returns the content of the file, or an empty string if it does not exist.
+func (w *Writer) Read(path string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(w.root, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("os.ReadFile[%s]: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// List returns the names of the entries of the directory, or nil if it does not exist.
+func (w *Writer) List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(w.root, dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir[%s]: %w", dir, err)
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
+}
+
+// Write writes the content to the file, restoring the keep regions of the existing file.
+// Regions which could not be restored are returned and written next to the file with
+// the .keep suffix, the report of a previous write is removed when there are none.
+func (w *Writer) Write(path, content string) ([]Conflict, error) {
+	existing, err := w.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("Read: %w", err)
+	}
+
+	regions, err := ExtractKeepRegions(existing)
+	if err != nil {
+		return nil, fmt.Errorf("ExtractKeepRegions[%s]: %w", path, err)
+	}
+
+	content, conflicts := RestoreKeepRegions(content, regions)
+	if err := w.write(path, content); err != nil {
+		return nil, err
+	}
+
+	if len(conflicts) == 0 {
+		reportPath := filepath.Join(w.root, path+keepReportSuffix)
+		if err := os.Remove(reportPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("os.Remove[%s]: %w", path+keepReportSuffix, err)
+		}
+		return nil, nil
+	}
+
+	if err := w.write(path+keepReportSuffix, ConflictReport(conflicts)); err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+func (w *Writer) write(path, content string) error {
+	fullPath := filepath.Join(w.root, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll[%s]: %w", filepath.Dir(fullPath), err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("os.WriteFile[%s]: %w", path, err)
+	}
+	return nil
+}
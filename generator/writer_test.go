@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter_Write(t *testing.T) {
+	existing := "func f() {\n\t// codegen:keep begin audit\n\tlog.Println()\n\t// codegen:keep end\n}\n"
+
+	testCases := map[string]struct {
+		content       string
+		wantConflicts int
+		wantReport    bool
+	}{
+		"restored region": {
+			content: "func f() {\n}\n",
+		},
+		"conflict": {
+			content:       "func g() {\n}\n",
+			wantConflicts: 1,
+			wantReport:    true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := NewWriter(dir)
+			if err := os.WriteFile(filepath.Join(dir, "user.go"), []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// the report of a previous write is stale when the regions are restored
+			if err := os.WriteFile(filepath.Join(dir, "user.go"+keepReportSuffix), []byte("stale"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			conflicts, err := w.Write("user.go", tc.content)
+			if err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if len(conflicts) != tc.wantConflicts {
+				t.Errorf("conflicts = %+v, want %d", conflicts, tc.wantConflicts)
+			}
+
+			report, err := w.Read("user.go" + keepReportSuffix)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if (report != "") != tc.wantReport || report == "stale" {
+				t.Errorf("report = %q, want a report %t", report, tc.wantReport)
+			}
+		})
+	}
+}
+
+func TestWriter_Write_noReport(t *testing.T) {
+	w := NewWriter(t.TempDir())
+	if _, err := w.Write("pkg/user.go", "package user\n"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if content, _ := w.Read("pkg/user.go"); content != "package user\n" {
+		t.Errorf("content = %q", content)
+	}
+}
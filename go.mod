@@ -3,13 +3,15 @@ module github.com/go-flexi/codegenerator
 go 1.20
 
 require (
+	github.com/atotto/clipboard v0.1.2
 	github.com/gdamore/tcell/v2 v2.7.1
+	github.com/lib/pq v1.10.9
 	github.com/pgavlin/femto v0.0.0-20201224065653-0c9d20f9cac4
 	github.com/rivo/tview v0.0.0-20240307173318-e804876934a1
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	github.com/atotto/clipboard v0.1.2 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
@@ -19,5 +21,4 @@ require (
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -1,31 +1,777 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/atotto/clipboard"
+	gen "github.com/go-flexi/codegenerator/generator"
 	"github.com/go-flexi/codegenerator/generator/backend"
+	"github.com/go-flexi/codegenerator/generator/batch"
+	"github.com/go-flexi/codegenerator/generator/frontend"
+	"github.com/go-flexi/codegenerator/generator/prompt"
 	"github.com/go-flexi/codegenerator/openai"
 	"github.com/go-flexi/codegenerator/ui/core"
+	_ "github.com/lib/pq"
+	"gopkg.in/yaml.v2"
 )
 
+// usage lists the commands.
+const usage = `usage: codegenerator <command> [arguments]
+
+commands:
+  core                                      generate an entity interactively
+  init [project-dir] [module-path]          scaffold go.mod and the shared packages
+  migrations <entity-dir|entity.json> [golang-migrate|goose]
+  grpc <entity-dir>                         write the protobuf service
+  permissions <entity-dir|entity.json> [policy...]
+  events <entity-dir|entity.json>           write the domain events
+  openapi <entity-dir>...                   write the OpenAPI documents
+  prompts list | show <name> | validate     manage the prompt templates
+  introspect <schema.sql|database.sqlite|postgres://...> [table...]
+  ir <entity-dir|schema.sql|schema.json>    print the intermediate representation
+  schema <schema.json|openapi.yaml> [schema-name...]
+  batch <manifest.yaml>                     generate the entities of a manifest
+  frontend <entity-dir> [hooks]             write the TypeScript client
+  coretest <entity-dir>                     write and run the core tests
+  storetest <entity-dir>                    write and run the store tests`
+
 func main() {
-	textToCopy := "Hello, clipboard! test"
-	err := clipboard.WriteAll(textToCopy)
-	if err != nil {
-		fmt.Println("Failed to copy text to the clipboard:", err)
-		return
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
 	}
 
-	generator := backend.NewGenerator(openai.NewAPI(
-		"api token",
-		openai.DefaultConfig(),
-	), "go-flexi", "ecom-backend")
+	library := prompt.NewLibrary(".")
+
+	// newGenerator renders the system prompt, only the commands which call the model create
+	// a generator, so the other commands work with broken prompt overrides
+	newGenerator := func() (*backend.Generator, error) {
+		module, err := readModule()
+		if err != nil {
+			return nil, err
+		}
+		examples, err := backend.FindExamples(gen.NewWriter("."), os.Getenv("CODEGEN_EXAMPLE_DIR"))
+		if err != nil {
+			return nil, fmt.Errorf("backend.FindExamples: %w", err)
+		}
+
+		g, err := backend.NewGenerator(openai.NewAPI(
+			os.Getenv("OPENAI_API_KEY"),
+			openai.DefaultConfig(),
+		), library, module, examples...)
+		if err != nil {
+			return nil, fmt.Errorf("backend.NewGenerator: %w", err)
+		}
+		g.SetWriter(gen.NewWriter("."))
+		return g, nil
+	}
 
+	var err error
 	switch os.Args[1] {
 	case "core":
-		core := core.NewCore(generator)
-		core.View()
+		err = runCore(newGenerator)
+	case "init":
+		err = initProject(os.Args[2:])
+	case "migrations":
+		err = migrations(os.Args[2:])
+	case "grpc":
+		err = grpc(os.Args[2:])
+	case "permissions":
+		err = permissions(os.Args[2:])
+	case "events":
+		err = events(os.Args[2:])
+	case "openapi":
+		err = openAPI(os.Args[2:])
+	case "prompts":
+		err = prompts(library, os.Args[2:])
+	case "introspect":
+		err = introspect(os.Args[2:])
+	case "ir":
+		err = printIR(os.Args[2:])
+	case "schema":
+		err = schema(os.Args[2:])
+	case "batch":
+		err = runBatch(newGenerator, os.Args[2:])
+	case "frontend":
+		err = frontendClient(library, os.Args[2:])
+	case "coretest":
+		err = coreTest(newGenerator, os.Args[2:])
+	case "storetest":
+		err = storeTest(newGenerator, os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+	default:
+		err = fmt.Errorf("unknown command %q\n\n%s", os.Args[1], usage)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// runCore generates an entity interactively: core
+func runCore(newGenerator func() (*backend.Generator, error)) error {
+	// the TUI copies the generated code, a headless machine has no clipboard
+	if err := clipboard.WriteAll("Hello, clipboard! test"); err != nil {
+		return fmt.Errorf("clipboard.WriteAll: %w", err)
+	}
+
+	generator, err := newGenerator()
+	if err != nil {
+		return err
+	}
+
+	core.NewCore(generator).View()
+	return nil
+}
+
+// coreTest writes the unit tests of the core of the entity package and runs them when
+// the go toolchain is installed: coretest <entity-dir>
+func coreTest(newGenerator func() (*backend.Generator, error), args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: coretest <entity-dir>")
+	}
+
+	generator, err := newGenerator()
+	if err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	files, err := readFiles(w, args[0], "model.go", "filter.go", "order.go", "core.go")
+	if err != nil {
+		return err
+	}
+	generator.AddFiles(files...)
+	if err := setEntity(generator, w, args[0]); err != nil {
+		return err
+	}
+
+	code, err := generator.CoreTestCall()
+	if err != nil {
+		return fmt.Errorf("generator.CoreTestCall: %w", err)
+	}
+
+	testFile := gen.File{Path: path.Join(args[0], "core_test.go"), Content: gen.ExtractCode(code)}
+	if err := writeFiles(w, []gen.File{testFile}); err != nil {
+		return err
+	}
+
+	return goTest("./" + args[0])
+}
+
+// storeTest writes the integration tests of the store of the entity package and runs them
+// when the go toolchain is installed: storetest <entity-dir>
+func storeTest(newGenerator func() (*backend.Generator, error), args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: storetest <entity-dir>")
+	}
+
+	generator, err := newGenerator()
+	if err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	files, err := readFiles(w, args[0], "model.go", "filter.go", "order.go", "core.go")
+	if err != nil {
+		return err
+	}
+
+	entity := path.Base(args[0])
+	storeDir := path.Join("store", entity+"db")
+	storeFiles, err := readFiles(w, storeDir, entity+"db.go")
+	if err != nil {
+		return err
+	}
+
+	migrations, err := w.List("migrations")
+	if err != nil {
+		return fmt.Errorf("List: %w", err)
+	}
+	// the golang-migrate up migrations and the goose migrations
+	upMigrations := []string{}
+	for _, name := range migrations {
+		if strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, ".down.sql") {
+			upMigrations = append(upMigrations, name)
+		}
+	}
+	migrationFiles, err := readFiles(w, "migrations", upMigrations...)
+	if err != nil {
+		return err
+	}
+
+	generator.AddFiles(append(append(files, storeFiles...), migrationFiles...)...)
+	if err := setEntity(generator, w, args[0]); err != nil {
+		return err
+	}
+
+	code, err := generator.StoreTestCall()
+	if err != nil {
+		return fmt.Errorf("generator.StoreTestCall: %w", err)
+	}
+
+	testFile := gen.File{Path: path.Join(storeDir, entity+"db_test.go"), Content: gen.ExtractCode(code)}
+	if err := writeFiles(w, []gen.File{testFile}); err != nil {
+		return err
+	}
+
+	return goTest("./" + storeDir)
+}
+
+// goTest runs go test for the packages when the go toolchain is installed.
+func goTest(pkgs ...string) error {
+	output, err := gen.GoTest(context.Background(), ".", pkgs...)
+	if errors.Is(err, gen.ErrNoToolchain) {
+		fmt.Println("go toolchain not found, skipped go test")
+		return nil
+	}
+	fmt.Print(output)
+	return err
+}
+
+// setEntity sets the fields and the relations of the entity package on the generator.
+func setEntity(generator *backend.Generator, w *gen.Writer, entityDir string) error {
+	pkg, err := backend.LoadPackage(w, entityDir)
+	if err != nil {
+		return fmt.Errorf("backend.LoadPackage: %w", err)
+	}
+	generator.SetEntity(pkg.Entity())
+	return nil
+}
+
+// readFiles reads the existing files of the directory.
+func readFiles(w *gen.Writer, dir string, names ...string) ([]gen.File, error) {
+	files := []gen.File{}
+	for _, name := range names {
+		content, err := w.Read(path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("Read: %w", err)
+		}
+		if content != "" {
+			files = append(files, gen.File{Path: path.Join(dir, name), Content: content})
+		}
+	}
+	return files, nil
+}
+
+// openAPI writes the OpenAPI documents of the entity packages and merges them into the
+// service document: openapi <entity-dir>...
+func openAPI(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: openapi <entity-dir>...")
+	}
+
+	module, err := readModule()
+	if err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	for _, entityDir := range args {
+		pkg, err := backend.LoadPackage(w, entityDir)
+		if err != nil {
+			return fmt.Errorf("backend.LoadPackage: %w", err)
+		}
+
+		files, err := backend.OpenAPIFiles(w, pkg, pkg.Entity(), module.Project)
+		if err != nil {
+			return fmt.Errorf("backend.OpenAPIFiles: %w", err)
+		}
+
+		if err := writeFiles(w, files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// events writes the domain events of the entity package with their tests, the migrations of
+// the package then create the outbox table: events <entity-dir|entity.json>
+func events(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: events <entity-dir|entity.json>")
+	}
+
+	module, err := readModule()
+	if err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	entities := []gen.Entity{}
+	if strings.HasSuffix(args[0], ".json") {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("os.ReadFile: %w", err)
+		}
+		if entities, err = gen.ParseEntities(data); err != nil {
+			return fmt.Errorf("gen.ParseEntities: %w", err)
+		}
+	} else {
+		pkg, err := backend.LoadPackage(w, args[0])
+		if err != nil {
+			return fmt.Errorf("backend.LoadPackage: %w", err)
+		}
+		entities = append(entities, pkg.Entity())
+	}
+
+	for _, entity := range entities {
+		files, err := backend.EventFiles(entity, module.Path)
+		if err != nil {
+			return fmt.Errorf("backend.EventFiles: %w", err)
+		}
+		if err := writeFiles(w, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permissions writes the permission checks of the entity package with their tests:
+// permissions <entity-dir> [role [action=role,role...] | owner <field> [admin-role...] | authorizer],
+// or of the entities of an edited intermediate representation with their policies:
+// permissions <entity.json>
+func permissions(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: permissions <entity-dir|entity.json> [role [action=role,role...] | owner <field> [admin-role...] | authorizer]")
+	}
+
+	module, err := readModule()
+	if err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	entities := []gen.Entity{}
+	if strings.HasSuffix(args[0], ".json") {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("os.ReadFile: %w", err)
+		}
+		if entities, err = gen.ParseEntities(data); err != nil {
+			return fmt.Errorf("gen.ParseEntities: %w", err)
+		}
+	} else {
+		pkg, err := backend.LoadPackage(w, args[0])
+		if err != nil {
+			return fmt.Errorf("backend.LoadPackage: %w", err)
+		}
+		entity := pkg.Entity()
+		if len(args) > 1 {
+			entity.Policy = &gen.Policy{Model: gen.PolicyModel(args[1])}
+		}
+		for i, arg := range args[2:] {
+			switch entity.Policy.Model {
+			case gen.RolePolicy:
+				action, roles, _ := strings.Cut(arg, "=")
+				if entity.Policy.Roles == nil {
+					entity.Policy.Roles = map[string][]string{}
+				}
+				entity.Policy.Roles[action] = strings.Split(roles, ",")
+			case gen.OwnerPolicy:
+				if i == 0 {
+					entity.Policy.Owner = arg
+				} else {
+					entity.Policy.Admins = append(entity.Policy.Admins, arg)
+				}
+			}
+		}
+		entity.SetDefaults()
+		entities = append(entities, entity)
+	}
+
+	for _, entity := range entities {
+		files, err := backend.PermissionFiles(entity, module.Path)
+		if err != nil {
+			return fmt.Errorf("backend.PermissionFiles: %w", err)
+		}
+		if err := writeFiles(w, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grpc writes the protobuf service of the entity package: grpc <entity-dir>
+func grpc(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: grpc <entity-dir>")
+	}
+
+	module, err := readModule()
+	if err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	pkg, err := backend.LoadPackage(w, args[0])
+	if err != nil {
+		return fmt.Errorf("backend.LoadPackage: %w", err)
+	}
+
+	files, err := backend.ProtoFiles(pkg, pkg.Entity(), module.Path)
+	if err != nil {
+		return fmt.Errorf("backend.ProtoFiles: %w", err)
+	}
+
+	return writeFiles(w, files)
+}
+
+// introspect prints the manifest of the tables of a pg_dump --schema-only or sqlite3 .schema
+// file, of a SQLite database file or of a PostgreSQL database:
+// introspect <schema.sql|database.sqlite|postgres://...> [table...]
+func introspect(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: introspect <schema.sql|database.sqlite|postgres://...> [table...]")
+	}
+
+	var tables []backend.Table
+	if strings.HasPrefix(args[0], "postgres://") || strings.HasPrefix(args[0], "postgresql://") {
+		db, err := sql.Open("postgres", args[0])
+		if err != nil {
+			return fmt.Errorf("sql.Open: %w", err)
+		}
+		defer db.Close()
+
+		if tables, err = backend.QueryTables(context.Background(), db, "public", args[1:]...); err != nil {
+			return fmt.Errorf("backend.QueryTables: %w", err)
+		}
+	} else {
+		ddl, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		if tables, err = backend.ParseSchema(string(ddl)); err != nil {
+			return fmt.Errorf("backend.ParseSchema: %w", err)
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range args[1:] {
+		wanted[name] = true
+	}
+
+	entities := []batch.Entity{}
+	for _, table := range tables {
+		if len(wanted) == 0 || wanted[table.Name] {
+			// the table exists already so no migrations are generated
+			entities = append(entities, batch.Entity{
+				Entity: backend.TableEntity(table),
+				Layers: []batch.Layer{batch.LayerCore, batch.LayerStore, batch.LayerHandler},
+			})
+		}
+	}
+
+	return printManifest(entities)
+}
+
+// schema prints the manifest of the object schemas of a JSON Schema or OpenAPI document:
+// schema <schema.json|openapi.yaml> [schema-name...]
+func schema(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: schema <schema.json|openapi.yaml> [schema-name...]")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	schemaEntities, err := batch.SchemaEntities(data, args[1:]...)
+	if err != nil {
+		return fmt.Errorf("batch.SchemaEntities: %w", err)
+	}
+
+	entities := make([]batch.Entity, len(schemaEntities))
+	for i, entity := range schemaEntities {
+		entities[i] = batch.Entity{Entity: entity}
+	}
+	return printManifest(entities)
+}
+
+// printIR prints the intermediate representation of the entities of a go entity package,
+// a SQL schema or a JSON Schema document as JSON: ir <entity-dir|schema.sql|schema.json>
+func printIR(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ir <entity-dir|schema.sql|schema.json>")
+	}
+
+	entities := []gen.Entity{}
+	if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+		pkg, err := backend.LoadPackage(gen.NewWriter("."), args[0])
+		if err != nil {
+			return fmt.Errorf("backend.LoadPackage: %w", err)
+		}
+		entities = append(entities, pkg.Entity())
+	} else {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("os.ReadFile: %w", err)
+		}
+
+		if strings.HasSuffix(args[0], ".sql") {
+			tables, err := backend.ParseSchema(string(data))
+			if err != nil {
+				return fmt.Errorf("backend.ParseSchema: %w", err)
+			}
+			for _, table := range tables {
+				entities = append(entities, backend.TableEntity(table))
+			}
+		} else if entities, err = batch.SchemaEntities(data); err != nil {
+			return fmt.Errorf("batch.SchemaEntities: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(entities, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printManifest prints the manifest of the entities to edit and pass to batch.
+func printManifest(entities []batch.Entity) error {
+	data, err := yaml.Marshal(batch.Manifest{Entities: entities})
+	if err != nil {
+		return fmt.Errorf("yaml.Marshal: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// runBatch generates the entities of the manifest: batch <manifest.yaml>
+func runBatch(newGenerator func() (*backend.Generator, error), args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: batch <manifest.yaml>")
+	}
+
+	module, err := readModule()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("os.ReadFile: %w", err)
+	}
+	manifest, err := batch.LoadManifest(data)
+	if err != nil {
+		return fmt.Errorf("batch.LoadManifest: %w", err)
+	}
+
+	newEntityGenerator := func() (batch.Generator, error) { return newGenerator() }
+	runner := batch.NewRunner(gen.NewWriter("."), newEntityGenerator, module.Path, module.Project)
+	summary := runner.Run(context.Background(), manifest)
+	fmt.Print(summary.String())
+
+	if failed := summary.Failed(); failed > 0 {
+		return fmt.Errorf("%d failed, see %s", failed, batch.LogDir)
+	}
+	return nil
+}
+
+// frontendClient writes the TypeScript types and fetch client of the entity package, and
+// the React Query hooks when asked: frontend <entity-dir> [hooks]
+func frontendClient(library *prompt.Library, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: frontend <entity-dir> [hooks]")
+	}
+
+	module, err := readModule()
+	if err != nil {
+		return err
+	}
+
+	generator, err := frontend.NewGenerator(openai.NewAPI(
+		os.Getenv("OPENAI_API_KEY"),
+		openai.DefaultConfig(),
+	), library, module)
+	if err != nil {
+		return fmt.Errorf("frontend.NewGenerator: %w", err)
+	}
+
+	w := gen.NewWriter(".")
+	files, err := readFiles(w, args[0], "model.go", "filter.go", "order.go")
+	if err != nil {
+		return err
+	}
+	generator.AddFiles(files...)
+
+	pkg, err := backend.LoadPackage(w, args[0])
+	if err != nil {
+		return fmt.Errorf("backend.LoadPackage: %w", err)
+	}
+	generator.SetEntity(pkg.Entity())
+
+	clientFiles, err := frontend.Scaffold(w)
+	if err != nil {
+		return fmt.Errorf("frontend.Scaffold: %w", err)
+	}
+
+	names := []string{"types.ts", "client.ts"}
+	calls := []func() (string, error){generator.TypesCall, generator.ClientCall}
+	if len(args) > 1 && args[1] == "hooks" {
+		names = append(names, "hooks.ts")
+		calls = append(calls, generator.HooksCall)
+	}
+	for i, call := range calls {
+		code, err := call()
+		if err != nil {
+			return fmt.Errorf("generator[%s]: %w", names[i], err)
+		}
+		clientFiles = append(clientFiles, gen.File{Path: generator.FilePath(names[i]), Content: gen.ExtractCode(code)})
+	}
+
+	return writeFiles(w, clientFiles)
+}
+
+// prompts manages the prompt templates: prompts list | show <name> | validate
+func prompts(library *prompt.Library, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: prompts list | show <name> | validate")
+	}
+
+	switch args[0] {
+	case "list":
+		list, err := library.List()
+		if err != nil {
+			return fmt.Errorf("library.List: %w", err)
+		}
+		for _, p := range list {
+			fmt.Printf("%-12s %s\n", p.Name, p.Source)
+		}
+	case "show":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: prompts show <name>")
+		}
+		text, source, err := library.Text(args[1])
+		if err != nil {
+			return fmt.Errorf("library.Text: %w", err)
+		}
+		fmt.Printf("# %s (%s)\n%s", args[1], source, text)
+	case "validate":
+		errs, err := library.Validate()
+		if err != nil {
+			return fmt.Errorf("library.Validate: %w", err)
+		}
+		for name, err := range errs {
+			fmt.Printf("%s: %v\n", name, err)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%d invalid prompts", len(errs))
+		}
+		fmt.Println("all prompts are valid")
+	default:
+		return fmt.Errorf("unknown prompts command %q", args[0])
+	}
+
+	return nil
+}
+
+// initProject scaffolds the shared packages of the generated entities, and go.mod with the
+// module path when the project has none: init [project-dir] [module-path]
+func initProject(args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	w := gen.NewWriter(dir)
+	module, err := gen.ReadModule(w)
+	switch {
+	case len(args) > 1:
+		if err == nil && module.Path != args[1] {
+			return fmt.Errorf("go.mod has the module %s, not %s", module.Path, args[1])
+		}
+		if module, err = gen.NewModule(args[1]); err != nil {
+			return fmt.Errorf("gen.NewModule: %w", err)
+		}
+	case errors.Is(err, gen.ErrNoModule):
+		return fmt.Errorf("usage: init [project-dir] <module-path>, %s has no go.mod", dir)
+	case err != nil:
+		return fmt.Errorf("gen.ReadModule: %w", err)
+	}
+
+	files, err := backend.Scaffold(w, module.Path)
+	if err != nil {
+		return fmt.Errorf("backend.Scaffold: %w", err)
+	}
+
+	return writeFiles(w, files)
+}
+
+// migrations writes the migrations of the entity package, or of the entities of an ir JSON
+// file: migrations <entity-dir|entity.json> [golang-migrate|goose]
+func migrations(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrations <entity-dir|entity.json> [golang-migrate|goose]")
+	}
+
+	format := backend.GolangMigrate
+	if len(args) > 1 {
+		format = backend.MigrationFormat(args[1])
+	}
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	w := gen.NewWriter(".")
+	if !strings.HasSuffix(args[0], ".json") {
+		files, err := backend.Migrations(w, args[0], format)
+		if err != nil {
+			return fmt.Errorf("backend.Migrations: %w", err)
+		}
+		return writeFiles(w, files)
+	}
+
+	// the edited intermediate representation printed by the ir command
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("os.ReadFile: %w", err)
+	}
+	entities, err := gen.ParseEntities(data)
+	if err != nil {
+		return fmt.Errorf("gen.ParseEntities: %w", err)
+	}
+	// the tables of the entities are ordered by their foreign keys
+	files, err := backend.EntitiesMigrations(w, entities, format)
+	if err != nil {
+		return fmt.Errorf("backend.EntitiesMigrations: %w", err)
+	}
+	return writeFiles(w, files)
+}
+
+// readModule returns the module of the go.mod of the project, the generated code imports it.
+func readModule() (gen.Module, error) {
+	module, err := gen.ReadModule(gen.NewWriter("."))
+	if err != nil {
+		return gen.Module{}, fmt.Errorf("gen.ReadModule: %w", err)
+	}
+	return module, nil
+}
+
+func writeFiles(w *gen.Writer, files []gen.File) error {
+	for _, file := range files {
+		conflicts, err := w.Write(file.Path, file.Content)
+		if err != nil {
+			return fmt.Errorf("Write: %w", err)
+		}
+		for _, conflict := range conflicts {
+			fmt.Println(file.Path + ": " + conflict.String())
+		}
+		fmt.Println("wrote " + file.Path)
 	}
+	return nil
 }
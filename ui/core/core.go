@@ -1,6 +1,8 @@
 package core
 
 import (
+	"strings"
+
 	"github.com/atotto/clipboard"
 
 	"github.com/go-flexi/codegenerator/generator/backend"
@@ -48,12 +50,22 @@ func (c *Core) hanldeModleEvent(e ui.Event, content string) {
 
 	c.app.SetFocus(c.userText.View())
 
+	// the entity of the written struct selects the keep regions of the existing model.go
+	if model, err := backend.ParseModelStruct(content); err == nil {
+		c.generator.SetEntity(backend.Package{Model: model}.Entity())
+	}
+
 	code, err := c.generator.FirstCall(content)
 	if err != nil {
 		c.generatedCode.Reset(err.Error())
 		return
 	}
 
+	// the layers are generated from the entity of the model, like the batch runner does
+	if model, err := backend.ParseModel(code); err == nil {
+		c.generator.SetEntity(backend.Package{Model: model}.Entity())
+	}
+
 	c.generatedCode.Reset(code)
 }
 
@@ -68,6 +80,49 @@ func (c *Core) handleUserTextEvent(e ui.Event, content string) {
 			return
 		}
 
+		c.generatedCode.Reset(code)
+	case ui.StoreEvent:
+		c.userText.Clear()
+
+		code, err := c.generator.StoreCall()
+		if err != nil {
+			c.generatedCode.Reset(err.Error())
+			return
+		}
+
+		c.generatedCode.Reset(code)
+	case ui.PackageEvent:
+		c.userText.Clear()
+
+		// the text before the event is the file of the package, e.g. filter.go:package
+		fileName := strings.TrimSpace(content)
+		if fileName == "" {
+			c.generatedCode.Reset("write the file of the package before :package, e.g. filter.go:package")
+			return
+		}
+
+		code, err := c.generator.PackageCall(fileName)
+		if err != nil {
+			c.generatedCode.Reset(err.Error())
+			return
+		}
+
+		c.generatedCode.Reset(code)
+	case ui.HandlerEvent:
+		c.userText.Clear()
+
+		// the text before the event selects the framework, e.g. chi:handler
+		framework := backend.Framework(strings.TrimSpace(content))
+		if framework == "" {
+			framework = backend.NetHTTP
+		}
+
+		code, err := c.generator.HandlerCall(framework)
+		if err != nil {
+			c.generatedCode.Reset(err.Error())
+			return
+		}
+
 		c.generatedCode.Reset(code)
 	}
 	if e == ui.SubmitEvent {
@@ -12,9 +12,12 @@ type Event string
 
 // list of events
 const (
-	SubmitEvent Event = ":submit"
-	NextEvent   Event = ":next"
-	CopyEvent   Event = ":copy"
+	SubmitEvent  Event = ":submit"
+	NextEvent    Event = ":next"
+	CopyEvent    Event = ":copy"
+	StoreEvent   Event = ":store"
+	HandlerEvent Event = ":handler"
+	PackageEvent Event = ":package"
 )
 
 type OnEvent func(e Event, content string)
@@ -121,5 +124,14 @@ func findEvent(content string) (Event, bool) {
 	if strings.HasSuffix(content, string(CopyEvent)) {
 		return CopyEvent, true
 	}
+	if strings.HasSuffix(content, string(StoreEvent)) {
+		return StoreEvent, true
+	}
+	if strings.HasSuffix(content, string(HandlerEvent)) {
+		return HandlerEvent, true
+	}
+	if strings.HasSuffix(content, string(PackageEvent)) {
+		return PackageEvent, true
+	}
 	return "", false
 }